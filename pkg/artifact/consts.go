@@ -19,4 +19,11 @@ const (
 	// UnknownConfigMediaType is the default mediaType used when no
 	// config media type is specified.
 	UnknownConfigMediaType = "application/vnd.unknown.config.v1+json"
+
+	// MediaTypeEmptyJSON is the media type of the empty JSON object "{}", used
+	// as the config of artifacts which have none.
+	MediaTypeEmptyJSON = "application/vnd.oci.empty.v1+json"
+
+	// MediaTypeArtifactManifest is the media type of an OCI artifact manifest
+	MediaTypeArtifactManifest = "application/vnd.oci.artifact.manifest.v1+json"
 )
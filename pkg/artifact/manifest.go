@@ -0,0 +1,67 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact
+
+import (
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ImageManifest is an OCI image manifest with the subject and artifactType
+// fields used to attach artifacts to an existing manifest. Without these
+// fields, it is encoded like an ocispec.Manifest.
+type ImageManifest struct {
+	specs.Versioned
+
+	// MediaType is the media type of the manifest
+	MediaType string `json:"mediaType,omitempty"`
+
+	// ArtifactType is the type of the artifact, when the config does not
+	// tell it
+	ArtifactType string `json:"artifactType,omitempty"`
+
+	// Config references a configuration object for a container, by digest.
+	// The referenced configuration object is a JSON blob that the runtime uses to set up the container.
+	Config ocispec.Descriptor `json:"config"`
+
+	// Layers is an indexed list of layers referenced by the manifest.
+	Layers []ocispec.Descriptor `json:"layers"`
+
+	// Subject is the manifest this manifest refers to
+	Subject *ocispec.Descriptor `json:"subject,omitempty"`
+
+	// Annotations contains arbitrary metadata for the image manifest.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI artifact manifest, describing an artifact by its type and
+// blobs, without config.
+type Manifest struct {
+	// MediaType is the media type of the manifest, MediaTypeArtifactManifest
+	MediaType string `json:"mediaType"`
+
+	// ArtifactType is the type of the artifact
+	ArtifactType string `json:"artifactType"`
+
+	// Blobs is the list of blobs of the artifact
+	Blobs []ocispec.Descriptor `json:"blobs,omitempty"`
+
+	// Subject is the manifest this manifest refers to
+	Subject *ocispec.Descriptor `json:"subject,omitempty"`
+
+	// Annotations contains arbitrary metadata for the artifact manifest
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
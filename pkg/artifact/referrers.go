@@ -0,0 +1,76 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact
+
+import (
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Descriptor describes a manifest referring to a subject, along with the type
+// of the artifact it holds.
+type Descriptor struct {
+	ocispec.Descriptor
+
+	// ArtifactType is the type of the artifact, which is the artifactType of
+	// the manifest or, if not set, the media type of its config.
+	ArtifactType string `json:"artifactType,omitempty"`
+}
+
+// Index is the image index listing the referrers of a subject, as returned by
+// the referrers API and stored under the referrers tag.
+type Index struct {
+	specs.Versioned
+
+	// MediaType is the media type of the index
+	MediaType string `json:"mediaType,omitempty"`
+
+	// Manifests are the descriptors of the referrers
+	Manifests []Descriptor `json:"manifests"`
+
+	// Annotations contains arbitrary metadata for the index
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ReferrersTag returns the tag under which registries without the referrers
+// API keep the index of the referrers of the subject.
+func ReferrersTag(subject digest.Digest) string {
+	algorithm := subject.Algorithm().String()
+	encoded := subject.Encoded()
+	if len(algorithm) > 32 {
+		algorithm = algorithm[:32]
+	}
+	if len(encoded) > 64 {
+		encoded = encoded[:64]
+	}
+	return algorithm + "-" + encoded
+}
+
+// FilterReferrers returns the referrers with the artifact type. If the type is
+// empty, all the referrers are returned.
+func FilterReferrers(referrers []Descriptor, artifactType string) []Descriptor {
+	if artifactType == "" {
+		return referrers
+	}
+	var filtered []Descriptor
+	for _, referrer := range referrers {
+		if referrer.ArtifactType == artifactType {
+			filtered = append(filtered, referrer)
+		}
+	}
+	return filtered
+}
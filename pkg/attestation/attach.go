@@ -0,0 +1,159 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+
+	"oras.land/oras-go/pkg/artifact"
+	orascontent "oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/oras"
+	"oras.land/oras-go/pkg/signature"
+	"oras.land/oras-go/pkg/target"
+)
+
+const (
+	// ArtifactType is the artifact type of the attestation manifests
+	ArtifactType = "application/vnd.in-toto.attestation.v1"
+
+	// MediaTypeEnvelope is the media type of the blob of an attestation
+	// manifest, holding the DSSE envelope
+	MediaTypeEnvelope = "application/vnd.dsse.envelope.v1+json"
+
+	// AnnotationPredicateType is the annotation of the envelope blob holding the
+	// predicate type of the statement, to filter attestations without fetching
+	// their envelope
+	AnnotationPredicateType = "in-toto.io/predicate-type"
+
+	// envelopeName is the name of the blob of an attestation manifest
+	envelopeName = "attestation.json"
+
+	// maxAttestationSize is the size over which the attestation manifests and
+	// envelopes are not fetched
+	maxAttestationSize = 16 * 1024 * 1024
+)
+
+// Attestation is an attestation of a subject, fetched by Fetch
+type Attestation struct {
+	// Descriptor is the descriptor of the attestation manifest
+	Descriptor ocispec.Descriptor
+	Envelope   *Envelope
+	Statement  *Statement
+}
+
+// Attach pushes the envelope of an in-toto statement about the subject
+// manifest as a referrer of the subject, with an image manifest whose
+// artifactType is ArtifactType, to the repository of the target. The
+// repository is a reference without tag nor digest, and may be empty for
+// targets without repositories such as the Memory and OCI stores. Returns the
+// descriptor of the attestation manifest.
+func Attach(ctx context.Context, to target.Target, repository string, subject ocispec.Descriptor, envelope *Envelope, opts ...oras.CopyOpt) (ocispec.Descriptor, error) {
+	statement, err := envelope.Statement()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if !statement.About(subject) {
+		return ocispec.Descriptor{}, errors.Wrap(ErrSubjectMismatch, subject.Digest.String())
+	}
+	p, err := json.Marshal(envelope)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	envelopeDesc := ocispec.Descriptor{
+		MediaType: MediaTypeEnvelope,
+		Digest:    digest.FromBytes(p),
+		Size:      int64(len(p)),
+		Annotations: map[string]string{
+			ocispec.AnnotationTitle: envelopeName,
+			AnnotationPredicateType: statement.PredicateType,
+		},
+	}
+	return signature.PushArtifact(ctx, to, repository, subject, ArtifactType, envelopeDesc, p, opts...)
+}
+
+// Fetch discovers the attestations of the subject manifest in the target, and
+// fetches and parses their statements. If predicateType is not empty, only the
+// attestations with this predicate type are returned. The target must
+// implement target.Discoverer.
+func Fetch(ctx context.Context, from target.Target, ref string, subject ocispec.Descriptor, predicateType string) ([]Attestation, error) {
+	discoverer, ok := from.(target.Discoverer)
+	if !ok {
+		return nil, oras.ErrDiscoverUnsupported
+	}
+	referrers, err := discoverer.Discover(ctx, ref, subject, ArtifactType)
+	if err != nil {
+		return nil, err
+	}
+	if len(referrers) == 0 {
+		return nil, nil
+	}
+	fetcher, err := from.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	fetcher = orascontent.NewVerifyingFetcher(fetcher)
+
+	var attestations []Attestation
+	for _, referrer := range referrers {
+		var manifest artifact.ImageManifest
+		if err := signature.FetchJSON(ctx, fetcher, referrer.Descriptor, maxAttestationSize, &manifest); err != nil {
+			return nil, err
+		}
+		envelopeDesc, err := findEnvelope(manifest)
+		if err != nil {
+			return nil, errors.Wrap(err, referrer.Digest.String())
+		}
+		if annotated, ok := envelopeDesc.Annotations[AnnotationPredicateType]; ok && predicateType != "" && annotated != predicateType {
+			continue
+		}
+		var envelope Envelope
+		if err := signature.FetchJSON(ctx, fetcher, envelopeDesc, maxAttestationSize, &envelope); err != nil {
+			return nil, err
+		}
+		statement, err := envelope.Statement()
+		if err != nil {
+			return nil, errors.Wrap(err, referrer.Digest.String())
+		}
+		if predicateType != "" && statement.PredicateType != predicateType {
+			continue
+		}
+		if !statement.About(subject) {
+			return nil, errors.Wrap(ErrSubjectMismatch, referrer.Digest.String())
+		}
+		attestations = append(attestations, Attestation{
+			Descriptor: referrer.Descriptor,
+			Envelope:   &envelope,
+			Statement:  statement,
+		})
+	}
+	return attestations, nil
+}
+
+// findEnvelope returns the descriptor of the envelope of an attestation
+// manifest
+func findEnvelope(manifest artifact.ImageManifest) (ocispec.Descriptor, error) {
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == MediaTypeEnvelope {
+			return layer, nil
+		}
+	}
+	return ocispec.Descriptor{}, ErrNoEnvelope
+}
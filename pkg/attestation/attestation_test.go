@@ -0,0 +1,120 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"reflect"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/attestation"
+	orascontent "oras.land/oras-go/pkg/content"
+)
+
+// storeImage stores an image with a single layer in the store under the ref
+func storeImage(t *testing.T, store *orascontent.Memory, ref, layer string) ocispec.Descriptor {
+	layerDesc, err := store.Add(layer, "", []byte(layer))
+	if err != nil {
+		t.Fatalf("could not add layer: %v", err)
+	}
+	manifest, manifestDesc, config, configDesc, err := orascontent.GenerateManifestAndConfig(nil, nil, layerDesc)
+	if err != nil {
+		t.Fatalf("could not generate manifest: %v", err)
+	}
+	store.Set(configDesc, config)
+	if err := store.StoreManifest(ref, manifestDesc, manifest); err != nil {
+		t.Fatalf("could not store manifest: %v", err)
+	}
+	return manifestDesc
+}
+
+func TestAttachAndFetch(t *testing.T) {
+	ctx := context.Background()
+	store := orascontent.NewMemory()
+	subject := storeImage(t, store, "image:v1", "layer")
+	other := storeImage(t, store, "image:v2", "other")
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	untrusted, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+
+	provenance := map[string]interface{}{
+		"builder":   map[string]interface{}{"id": "https://ci.example.com/builder"},
+		"buildType": "https://example.com/build/v1",
+	}
+	statements := []*attestation.Statement{
+		attestation.NewStatement("image:v1", subject, attestation.PredicateTypeSLSAProvenance, provenance),
+		attestation.NewStatement("image:v1", subject, "https://example.com/test-result/v1", map[string]interface{}{"passed": true}),
+	}
+	for _, statement := range statements {
+		envelope, err := attestation.NewEnvelope(statement, private)
+		if err != nil {
+			t.Fatalf("could not create envelope: %v", err)
+		}
+		if _, err := attestation.Attach(ctx, store, "", subject, envelope); err != nil {
+			t.Fatalf("could not attach %s: %v", statement.PredicateType, err)
+		}
+		if _, err := attestation.Attach(ctx, store, "", other, envelope); !errors.Is(err, attestation.ErrSubjectMismatch) {
+			t.Errorf("attach to other subject error = %v, expected %v", err, attestation.ErrSubjectMismatch)
+		}
+	}
+
+	attestations, err := attestation.Fetch(ctx, store, "image:v1", subject, "")
+	if err != nil {
+		t.Fatalf("could not fetch attestations: %v", err)
+	}
+	if len(attestations) != len(statements) {
+		t.Fatalf("fetched %d attestations, expected %d", len(attestations), len(statements))
+	}
+
+	attestations, err = attestation.Fetch(ctx, store, "image:v1", subject, attestation.PredicateTypeSLSAProvenance)
+	if err != nil {
+		t.Fatalf("could not fetch provenance: %v", err)
+	}
+	if len(attestations) != 1 {
+		t.Fatalf("fetched %d provenance attestations, expected 1", len(attestations))
+	}
+	fetched := attestations[0]
+	if fetched.Statement.Type != attestation.StatementType || !fetched.Statement.About(subject) {
+		t.Errorf("fetched statement %+v is not about %s", fetched.Statement, subject.Digest)
+	}
+	if !reflect.DeepEqual(fetched.Statement.Predicate, provenance) {
+		t.Errorf("fetched predicate %v, expected %v", fetched.Statement.Predicate, provenance)
+	}
+	if err := fetched.Envelope.Verify(public); err != nil {
+		t.Errorf("envelope not verified: %v", err)
+	}
+	if err := fetched.Envelope.Verify(untrusted); !errors.Is(err, attestation.ErrNoValidSignature) {
+		t.Errorf("untrusted key error = %v, expected %v", err, attestation.ErrNoValidSignature)
+	}
+
+	attestations, err = attestation.Fetch(ctx, store, "image:v2", other, "")
+	if err != nil {
+		t.Fatalf("could not fetch attestations of other subject: %v", err)
+	}
+	if len(attestations) != 0 {
+		t.Errorf("fetched %d attestations of other subject, expected none", len(attestations))
+	}
+}
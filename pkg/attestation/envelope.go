@@ -0,0 +1,116 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"oras.land/oras-go/pkg/signature"
+)
+
+// PayloadType is the payload type of the envelopes of in-toto statements
+const PayloadType = "application/vnd.in-toto+json"
+
+// Envelope is a DSSE envelope, holding a payload and its signatures
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     []byte      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a signature of a DSSE envelope
+type Signature struct {
+	// KeyID identifies the public key verifying the signature, see
+	// signature.KeyID
+	KeyID string `json:"keyid"`
+	// Sig is the signature of the pre-authentication encoding of the payload:
+	// ASN.1 encoded over its SHA-256 for ECDSA keys, over the encoding itself
+	// for Ed25519 keys
+	Sig []byte `json:"sig"`
+}
+
+// NewEnvelope wraps the statement into a DSSE envelope, signed with each of
+// the ECDSA or Ed25519 signers
+func NewEnvelope(statement *Statement, signers ...crypto.Signer) (*Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+	e := &Envelope{
+		PayloadType: PayloadType,
+		Payload:     payload,
+		Signatures:  []Signature{},
+	}
+	message := e.pae()
+	for _, signer := range signers {
+		keyID, err := signature.KeyID(signer.Public())
+		if err != nil {
+			return nil, err
+		}
+		sig, err := signature.SignBytes(signer, message)
+		if err != nil {
+			return nil, err
+		}
+		e.Signatures = append(e.Signatures, Signature{
+			KeyID: keyID,
+			Sig:   sig,
+		})
+	}
+	return e, nil
+}
+
+// Statement decodes the in-toto statement of the envelope
+func (e *Envelope) Statement() (*Statement, error) {
+	if e.PayloadType != PayloadType {
+		return nil, errors.Wrap(ErrUnsupportedPayloadType, e.PayloadType)
+	}
+	var statement Statement
+	if err := json.Unmarshal(e.Payload, &statement); err != nil {
+		return nil, err
+	}
+	if statement.Type != StatementType {
+		return nil, errors.Wrap(ErrUnsupportedPayloadType, statement.Type)
+	}
+	return &statement, nil
+}
+
+// Verify succeeds if one of the signatures of the envelope is a valid
+// signature of one of the ECDSA or Ed25519 keys
+func (e *Envelope) Verify(keys ...crypto.PublicKey) error {
+	trusted := make(map[string]crypto.PublicKey, len(keys))
+	for _, key := range keys {
+		keyID, err := signature.KeyID(key)
+		if err != nil {
+			return err
+		}
+		trusted[keyID] = key
+	}
+	message := e.pae()
+	for _, sig := range e.Signatures {
+		if key, ok := trusted[sig.KeyID]; ok && signature.VerifyBytes(key, message, sig.Sig) {
+			return nil
+		}
+	}
+	return ErrNoValidSignature
+}
+
+// pae returns the pre-authentication encoding of the payload, which is signed
+func (e *Envelope) pae() []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(e.PayloadType), e.PayloadType, len(e.Payload), e.Payload))
+}
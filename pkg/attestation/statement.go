@@ -0,0 +1,69 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation
+
+import (
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// StatementType is the type of the in-toto statements
+	StatementType = "https://in-toto.io/Statement/v0.1"
+
+	// PredicateTypeSLSAProvenance is the predicate type of the SLSA provenance
+	PredicateTypeSLSAProvenance = "https://slsa.dev/provenance/v0.2"
+)
+
+// Statement is an in-toto statement: a predicate about one or more subjects
+type Statement struct {
+	Type          string      `json:"_type"`
+	Subject       []Subject   `json:"subject"`
+	PredicateType string      `json:"predicateType"`
+	Predicate     interface{} `json:"predicate"`
+}
+
+// Subject is a subject of an in-toto statement, identified by its digests
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// NewStatement creates an in-toto statement about the manifest desc, named
+// name, such as its reference
+func NewStatement(name string, desc ocispec.Descriptor, predicateType string, predicate interface{}) *Statement {
+	return &Statement{
+		Type: StatementType,
+		Subject: []Subject{
+			{
+				Name: name,
+				Digest: map[string]string{
+					desc.Digest.Algorithm().String(): desc.Digest.Encoded(),
+				},
+			},
+		},
+		PredicateType: predicateType,
+		Predicate:     predicate,
+	}
+}
+
+// About returns whether the manifest desc is a subject of the statement
+func (s *Statement) About(desc ocispec.Descriptor) bool {
+	for _, subject := range s.Subject {
+		if subject.Digest[desc.Digest.Algorithm().String()] == desc.Digest.Encoded() {
+			return true
+		}
+	}
+	return false
+}
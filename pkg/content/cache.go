@@ -0,0 +1,326 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// CacheOptions provide configuration options to a Cache
+type CacheOptions struct {
+	// Root is the directory holding the cached blobs
+	Root string
+	// MaxSize is the maximum total size in bytes of the cached blobs. When it is
+	// exceeded, the least recently used blobs are removed. 0 means no limit.
+	MaxSize int64
+	// ResolveTTL is how long the result of resolving a ref is reused. 0 means
+	// every Resolve goes to the wrapped resolver.
+	ResolveTTL time.Duration
+}
+
+// Cache wraps a target, typically a Registry, with a local content-addressable
+// cache of blobs. Fetch serves blobs from the cache when present, and adds them
+// to the cache while streaming them from the wrapped target otherwise. Resolve
+// and Pusher go to the wrapped target.
+type Cache struct {
+	remotes.Resolver
+
+	root    string
+	maxSize int64
+	ttl     time.Duration
+
+	lock     sync.Mutex
+	lru      *lruCache
+	resolved map[string]cachedResolve
+}
+
+type cachedResolve struct {
+	name    string
+	desc    ocispec.Descriptor
+	expires time.Time
+}
+
+// NewCache creates a new Cache in front of resolver, loading the blobs already
+// present in the cache directory.
+func NewCache(resolver remotes.Resolver, opts CacheOptions) (*Cache, error) {
+	c := &Cache{
+		Resolver: resolver,
+		root:     opts.Root,
+		maxSize:  opts.MaxSize,
+		ttl:      opts.ResolveTTL,
+		lru:      newLRUCache(),
+		resolved: make(map[string]cachedResolve),
+	}
+	if err := os.MkdirAll(c.ingestDir(), 0755); err != nil {
+		return nil, err
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	c.prune()
+	return c, nil
+}
+
+// load adds the blobs found in the cache directory, oldest first
+func (c *Cache) load() error {
+	type blob struct {
+		digest  digest.Digest
+		size    int64
+		modTime time.Time
+	}
+	var blobs []blob
+	blobsDir := filepath.Join(c.root, "blobs")
+	if err := filepath.Walk(blobsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(blobsDir, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+		if len(parts) != 2 {
+			return nil
+		}
+		dgst := digest.NewDigestFromEncoded(digest.Algorithm(parts[0]), parts[1])
+		if err := dgst.Validate(); err != nil {
+			return nil
+		}
+		blobs = append(blobs, blob{
+			digest:  dgst,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		return nil
+	}); err != nil {
+		return err
+	}
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].modTime.Before(blobs[j].modTime)
+	})
+	for _, b := range blobs {
+		c.lru.add(b.digest, b.size)
+	}
+	return nil
+}
+
+// Resolve resolves the ref with the wrapped resolver, reusing the result for
+// the configured ResolveTTL.
+func (c *Cache) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	if c.ttl > 0 {
+		c.lock.Lock()
+		resolved, ok := c.resolved[ref]
+		c.lock.Unlock()
+		if ok && time.Now().Before(resolved.expires) {
+			return resolved.name, resolved.desc, nil
+		}
+	}
+	name, desc, err := c.Resolver.Resolve(ctx, ref)
+	if err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+	if c.ttl > 0 {
+		c.lock.Lock()
+		c.resolved[ref] = cachedResolve{
+			name:    name,
+			desc:    desc,
+			expires: time.Now().Add(c.ttl),
+		}
+		c.lock.Unlock()
+	}
+	return name, desc, nil
+}
+
+// Fetcher returns a fetcher for the ref that goes through the cache
+func (c *Cache) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	fetcher, err := c.Resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &cacheFetcher{
+		cache:   c,
+		fetcher: fetcher,
+	}, nil
+}
+
+// Size returns the total size in bytes of the cached blobs
+func (c *Cache) Size() int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.lru.size
+}
+
+func (c *Cache) blobPath(dgst digest.Digest) string {
+	return filepath.Join(c.root, filepath.FromSlash(blobPath(dgst)))
+}
+
+func (c *Cache) ingestDir() string {
+	return filepath.Join(c.root, "ingest")
+}
+
+// open opens a cached blob, marking it as recently used
+func (c *Cache) open(desc ocispec.Descriptor) (io.ReadCloser, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.lru.touch(desc.Digest) {
+		return nil, false
+	}
+	path := c.blobPath(desc.Digest)
+	file, err := os.Open(path)
+	if err != nil {
+		c.lru.remove(desc.Digest)
+		return nil, false
+	}
+	// persist the recency of use across restarts
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return file, true
+}
+
+// commit moves a fully fetched and verified blob into the cache
+func (c *Cache) commit(desc ocispec.Descriptor, tempPath string) error {
+	path := c.blobPath(desc.Digest)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	c.lru.add(desc.Digest, desc.Size)
+	c.lock.Unlock()
+	c.prune()
+	return nil
+}
+
+// prune removes the least recently used blobs until the cache is within MaxSize
+func (c *Cache) prune() {
+	if c.maxSize <= 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, dgst := range c.lru.evict(c.maxSize, nil) {
+		if err := os.Remove(c.blobPath(dgst)); err != nil && !os.IsNotExist(err) {
+			log.L.WithError(err).Warnf("unable to remove cached blob %s", dgst)
+		}
+	}
+}
+
+// cacheFetcher fetches from the cache, falling back to the wrapped fetcher
+type cacheFetcher struct {
+	cache   *Cache
+	fetcher remotes.Fetcher
+}
+
+// Fetch get an io.ReadCloser for the specific content
+func (f *cacheFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	if err := desc.Digest.Validate(); err != nil {
+		return nil, err
+	}
+	if rc, ok := f.cache.open(desc); ok {
+		return rc, nil
+	}
+	rc, err := f.fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ioutil.TempFile(f.cache.ingestDir(), TempFilePattern)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("unable to cache blob")
+		return rc, nil
+	}
+	return &cacheReader{
+		ReadCloser: rc,
+		cache:      f.cache,
+		desc:       desc,
+		file:       file,
+		digester:   desc.Digest.Algorithm().Digester(),
+	}, nil
+}
+
+// cacheReader copies the content read from the wrapped reader to a temporary
+// file, which is added to the cache once the whole content is read and verified
+type cacheReader struct {
+	io.ReadCloser
+	cache    *Cache
+	desc     ocispec.Descriptor
+	file     *os.File
+	digester digest.Digester
+	size     int64
+}
+
+func (r *cacheReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if r.file != nil && n > 0 {
+		if _, werr := r.file.Write(p[:n]); werr != nil {
+			r.discard()
+		} else {
+			r.digester.Hash().Write(p[:n])
+			r.size += int64(n)
+		}
+	}
+	if err == io.EOF && r.file != nil {
+		if r.size == r.desc.Size && r.digester.Digest() == r.desc.Digest {
+			tempPath := r.file.Name()
+			r.file.Close()
+			r.file = nil
+			if cerr := r.cache.commit(r.desc, tempPath); cerr != nil {
+				os.Remove(tempPath)
+			}
+		} else {
+			r.discard()
+		}
+	}
+	return n, err
+}
+
+func (r *cacheReader) Close() error {
+	r.discard()
+	return r.ReadCloser.Close()
+}
+
+// discard stops caching the content
+func (r *cacheReader) discard() {
+	if r.file == nil {
+		return
+	}
+	r.file.Close()
+	os.Remove(r.file.Name())
+	r.file = nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/remotes"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/content"
+)
+
+// countingResolver counts the fetches going to the wrapped Memory store
+type countingResolver struct {
+	*content.Memory
+	fetches int
+}
+
+func (r *countingResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	return r, nil
+}
+
+func (r *countingResolver) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	r.fetches++
+	return r.Memory.Fetch(ctx, desc)
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	upstream := &countingResolver{Memory: content.NewMemory()}
+	a, _ := upstream.Add("a", "", []byte("aaaa"))
+	b, _ := upstream.Add("b", "", []byte("bbbb"))
+	manifest, manifestDesc, _, _, err := content.GenerateManifestAndConfig(nil, nil, a, b)
+	if err != nil {
+		t.Fatalf("unable to generate manifest: %v", err)
+	}
+	upstream.StoreManifest("ref", manifestDesc, manifest)
+
+	root, err := ioutil.TempDir("", "oras_cache")
+	if err != nil {
+		t.Fatalf("unable to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+	cache, err := content.NewCache(upstream, content.CacheOptions{
+		Root:       root,
+		MaxSize:    6,
+		ResolveTTL: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("unable to create cache: %v", err)
+	}
+
+	fetch := func(desc ocispec.Descriptor) string {
+		fetcher, err := cache.Fetcher(ctx, "ref")
+		if err != nil {
+			t.Fatalf("unable to get fetcher: %v", err)
+		}
+		rc, err := fetcher.Fetch(ctx, desc)
+		if err != nil {
+			t.Fatalf("unable to fetch: %v", err)
+		}
+		defer rc.Close()
+		b, err := ioutil.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("unable to read: %v", err)
+		}
+		return string(b)
+	}
+
+	// miss then hit
+	if actual := fetch(a); actual != "aaaa" {
+		t.Errorf("mismatched content %q", actual)
+	}
+	if actual := fetch(a); actual != "aaaa" {
+		t.Errorf("mismatched cached content %q", actual)
+	}
+	if upstream.fetches != 1 {
+		t.Errorf("expected 1 upstream fetch, got %d", upstream.fetches)
+	}
+
+	// b does not fit alongside a, so a is pruned
+	fetch(b)
+	if cache.Size() != b.Size {
+		t.Errorf("unexpected cache size %d", cache.Size())
+	}
+	fetch(a)
+	if upstream.fetches != 3 {
+		t.Errorf("expected 3 upstream fetches, got %d", upstream.fetches)
+	}
+
+	// the cache directory is reloaded
+	reloaded, err := content.NewCache(upstream, content.CacheOptions{Root: root})
+	if err != nil {
+		t.Fatalf("unable to reload cache: %v", err)
+	}
+	if reloaded.Size() != a.Size {
+		t.Errorf("unexpected reloaded cache size %d", reloaded.Size())
+	}
+
+	// resolutions are reused within the TTL
+	_, desc, err := cache.Resolve(ctx, "ref")
+	if err != nil || desc.Digest != manifestDesc.Digest {
+		t.Fatalf("unable to resolve: %v", err)
+	}
+	upstream.StoreManifest("ref", a, []byte("aaaa"))
+	if _, desc, _ := cache.Resolve(ctx, "ref"); desc.Digest != manifestDesc.Digest {
+		t.Errorf("resolution not reused within TTL")
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package content
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/containerd/containerd/archive/compression"
+	"github.com/containerd/containerd/content"
+	"github.com/pkg/errors"
+)
+
+// Compression is a compression algorithm of layers
+type Compression string
+
+const (
+	// CompressionNone leaves the layers uncompressed
+	CompressionNone Compression = "none"
+	// CompressionGzip compresses the layers with gzip
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses the layers with zstd
+	CompressionZstd Compression = "zstd"
+)
+
+// algorithm returns the containerd compression algorithm
+func (c Compression) algorithm() (compression.Compression, error) {
+	switch c {
+	case CompressionNone:
+		return compression.Uncompressed, nil
+	case CompressionGzip:
+		return compression.Gzip, nil
+	case CompressionZstd:
+		return compression.Zstd, nil
+	}
+	return compression.Uncompressed, errors.Wrapf(ErrUnsupported, "compression %q", string(c))
+}
+
+// NewRecompressWriter wrap a writer with a recompression, so that the stream,
+// either uncompressed or compressed with gzip or zstd, is passed through to the
+// writer with the given compression.
+//
+// By default, it calculates the hash when writing. If the option `skipHash` is true,
+// it will skip doing the hash. Skipping the hash is intended to be used only
+// if you are confident about the validity of the data being passed to the writer,
+// and wish to save on the hashing time.
+func NewRecompressWriter(writer content.Writer, c Compression, opts ...WriterOpt) (content.Writer, error) {
+	algorithm, err := c.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	// process opts for default
+	wOpts := DefaultWriterOpts()
+	for _, opt := range opts {
+		if err := opt(&wOpts); err != nil {
+			return nil, err
+		}
+	}
+	return NewPassthroughWriter(writer, func(r io.Reader, w io.Writer, done chan<- error) {
+		err := recompress(r, w, algorithm, wOpts.Blocksize)
+		if err != nil {
+			// drain the input so that the writes do not block
+			_, _ = io.Copy(ioutil.Discard, r)
+		}
+		done <- err
+	}, opts...), nil
+}
+
+// recompress decompresses r, and writes it to w with the compression algorithm
+func recompress(r io.Reader, w io.Writer, algorithm compression.Compression, blocksize int) error {
+	dr, err := compression.DecompressStream(r)
+	if err != nil {
+		return fmt.Errorf("error creating decompression reader: %v", err)
+	}
+	defer dr.Close()
+	cw, err := compression.CompressStream(w, algorithm)
+	if err != nil {
+		return fmt.Errorf("error creating compression writer: %v", err)
+	}
+	b := make([]byte, blocksize)
+	if _, err := io.CopyBuffer(cw, dr, b); err != nil {
+		cw.Close()
+		return fmt.Errorf("RecompressWriter: error writing to underlying writer: %v", err)
+	}
+	return cw.Close()
+}
@@ -0,0 +1,77 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package content_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/containerd/containerd/archive/compression"
+	ctrcontent "github.com/containerd/containerd/content"
+	digest "github.com/opencontainers/go-digest"
+
+	"oras.land/oras-go/pkg/content"
+)
+
+func TestRecompressWriter(t *testing.T) {
+	ctx := context.Background()
+	if _, err := content.NewRecompressWriter(content.NewIoContentWriter(nil), content.Compression("lz4")); err == nil {
+		t.Fatal("expected error for unsupported compression")
+	}
+
+	// recompress the test content through all the compressions and back
+	tests := []struct {
+		compression content.Compression
+		algorithm   compression.Compression
+	}{
+		{content.CompressionGzip, compression.Gzip},
+		{content.CompressionZstd, compression.Zstd},
+		{content.CompressionNone, compression.Uncompressed},
+	}
+	input := testContent
+	for _, tt := range tests {
+		c := tt.compression
+		var buf bytes.Buffer
+		w, err := content.NewRecompressWriter(content.NewIoContentWriter(&buf), c)
+		if err != nil {
+			t.Fatalf("unexpected error creating %s writer: %v", c, err)
+		}
+		if err := ctrcontent.Copy(ctx, w, bytes.NewReader(input), int64(len(input)), digest.FromBytes(input)); err != nil {
+			t.Fatalf("unexpected error recompressing with %s: %v", c, err)
+		}
+		if w.Digest() != digest.FromBytes(input) {
+			t.Errorf("%s: mismatched input digest %s", c, w.Digest())
+		}
+
+		r, err := compression.DecompressStream(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("unexpected error decompressing %s: %v", c, err)
+		}
+		if r.GetCompression() != tt.algorithm {
+			t.Errorf("%s: unexpected compression %v", c, r.GetCompression())
+		}
+		output, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("unexpected error reading %s: %v", c, err)
+		}
+		if !bytes.Equal(output, testContent) {
+			t.Errorf("%s: mismatched content %q", c, output)
+		}
+		input = buf.Bytes()
+	}
+}
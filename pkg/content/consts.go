@@ -37,6 +37,12 @@ const (
 	AnnotationDigest = "io.deis.oras.content.digest"
 	// AnnotationUnpack is the annotation key for indication of unpacking
 	AnnotationUnpack = "io.deis.oras.content.unpack"
+	// AnnotationEncryptionKeysJWE is the ocicrypt annotation key for the
+	// private options of an encrypted layer, wrapped in a JWE for the recipients
+	AnnotationEncryptionKeysJWE = "org.opencontainers.image.enc.keys.jwe"
+	// AnnotationEncryptionPubOpts is the ocicrypt annotation key for the public
+	// options of an encrypted layer
+	AnnotationEncryptionPubOpts = "org.opencontainers.image.enc.pubopts"
 )
 
 const (
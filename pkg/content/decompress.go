@@ -17,12 +17,13 @@ package content
 
 import (
 	"context"
-	"errors"
+	"crypto/rsa"
 	"strings"
 
 	ctrcontent "github.com/containerd/containerd/content"
 	"github.com/containerd/containerd/remotes"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
 )
 
 // Decompress store to decompress content and extract from tar, if needed, wrapping
@@ -50,6 +51,7 @@ type Decompress struct {
 	pusher              remotes.Pusher
 	blocksize           int
 	multiWriterIngester bool
+	decryptionKeys      []*rsa.PrivateKey
 }
 
 func NewDecompress(pusher remotes.Pusher, opts ...WriterOpt) Decompress {
@@ -62,13 +64,14 @@ func NewDecompress(pusher remotes.Pusher, opts ...WriterOpt) Decompress {
 		}
 	}
 
-	return Decompress{pusher, wOpts.Blocksize, wOpts.MultiWriterIngester}
+	return Decompress{pusher, wOpts.Blocksize, wOpts.MultiWriterIngester, wOpts.DecryptionKeys}
 }
 
 // Push get a content.Writer
 func (d Decompress) Push(ctx context.Context, desc ocispec.Descriptor) (ctrcontent.Writer, error) {
 	// the logic is straightforward:
 	// - if there is a desc in the opts, and the mediatype is tar or tar+gzip, then pass the correct decompress writer
+	// - if the mediatype is also encrypted, decrypt before decompressing
 	// - else, pass the regular writer
 	var (
 		writer        ctrcontent.Writer
@@ -85,10 +88,15 @@ func (d Decompress) Push(ctx context.Context, desc ocispec.Descriptor) (ctrconte
 		}
 	}
 
-	// figure out if compression and/or archive exists
+	// figure out if encryption, compression and/or archive exists
 	// before we pass it down, we need to strip anything we are removing here
 	// and possibly update the digest, since the store indexes things by digest
-	hasGzip, hasTar, modifiedMediaType := checkCompression(desc.MediaType)
+	encrypted, modifiedMediaType := checkEncryption(desc.MediaType)
+	if encrypted && len(d.decryptionKeys) == 0 {
+		return nil, errors.Wrapf(ErrNoDecryptionKey, "%s: encrypted layer", desc.Digest)
+	}
+	encryptedDesc := desc
+	hasGzip, hasTar, modifiedMediaType := checkCompression(modifiedMediaType)
 	desc.MediaType = modifiedMediaType
 	// determine if we pass it blocksize, only if positive
 	writerOpts := []WriterOpt{}
@@ -123,9 +131,26 @@ func (d Decompress) Push(ctx context.Context, desc ocispec.Descriptor) (ctrconte
 		}
 		writer = NewGunzipWriter(writer, writerOpts...)
 	}
+	if encrypted {
+		decryptWriter, err := NewDecryptWriter(writer, encryptedDesc, d.decryptionKeys, writerOpts...)
+		if err != nil {
+			writer.Close()
+			return nil, err
+		}
+		writer = decryptWriter
+	}
 	return writer, nil
 }
 
+// checkEncryption check if the mediatype is encrypted. Returns if it is
+// encrypted, as well as the media type without the encrypted suffix.
+func checkEncryption(mediaType string) (bool, string) {
+	if strings.HasSuffix(mediaType, EncryptedSuffix) {
+		return true, mediaType[:len(mediaType)-len(EncryptedSuffix)]
+	}
+	return false, mediaType
+}
+
 // checkCompression check if the mediatype uses gzip compression or tar.
 // Returns if it has gzip and/or tar, as well as the base media type without
 // those suffixes.
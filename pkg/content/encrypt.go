@@ -0,0 +1,343 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package content
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/containerd/containerd/content"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// EncryptedSuffix is the suffix of the media types of encrypted layers, as
+// defined by ocicrypt
+const EncryptedSuffix = "+encrypted"
+
+// CipherAES256CTR is the ocicrypt name of the AES-256 cipher in CTR mode
+// authenticated with HMAC-SHA256, the only one supported
+const CipherAES256CTR = "AES_256_CTR_HMAC_SHA256"
+
+const (
+	keySize     = 32
+	nonceOption = "nonce"
+)
+
+// publicOptions are the options of an encrypted layer stored in the clear, in
+// the AnnotationEncryptionPubOpts annotation
+type publicOptions struct {
+	Cipher        string            `json:"cipher"`
+	HMAC          []byte            `json:"hmac"`
+	CipherOptions map[string][]byte `json:"cipheroptions"`
+}
+
+// privateOptions are the options of an encrypted layer wrapped for the
+// recipients, in the AnnotationEncryptionKeysJWE annotation
+type privateOptions struct {
+	SymmetricKey  []byte            `json:"symkey"`
+	Digest        digest.Digest     `json:"digest"`
+	CipherOptions map[string][]byte `json:"cipheroptions"`
+}
+
+// EncryptedMediaType returns the media type of a layer of the media type once
+// encrypted
+func EncryptedMediaType(mediaType string) string {
+	return mediaType + EncryptedSuffix
+}
+
+// NewEncryptWriter wrap a writer with an encryption, so that the stream is
+// encrypted with a random key before being passed through, in the layout of
+// ocicrypt: it is encrypted with AES-256 in CTR mode, and its HMAC-SHA256 is
+// stored in the public options of the layer. The key is stored in the private
+// options of the layer, which are wrapped in a JWE for the recipients with
+// RSA-OAEP. The options are returned as the annotations to add to the
+// descriptor of the encrypted layer, whose media type is given by
+// EncryptedMediaType. As they depend on the whole stream, the annotations are
+// only set once the writer is committed.
+//
+// By default, it calculates the hash when writing. If the option `skipHash` is true,
+// it will skip doing the hash. Skipping the hash is intended to be used only
+// if you are confident about the validity of the data being passed to the writer,
+// and wish to save on the hashing time.
+func NewEncryptWriter(writer content.Writer, recipients []*rsa.PublicKey, opts ...WriterOpt) (content.Writer, map[string]string, error) {
+	if len(recipients) == 0 {
+		return nil, nil, errors.New("no recipient")
+	}
+	// process opts for default
+	wOpts := DefaultWriterOpts()
+	for _, opt := range opts {
+		if err := opt(&wOpts); err != nil {
+			return nil, nil, err
+		}
+	}
+	key := make([]byte, keySize)
+	nonce := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, err
+	}
+	stream, err := newStream(key, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	annotations := make(map[string]string)
+	return NewPassthroughWriter(writer, func(r io.Reader, w io.Writer, done chan<- error) {
+		mac := hmac.New(sha256.New, key)
+		digester := digest.Canonical.Digester()
+		b := make([]byte, wOpts.Blocksize)
+		for {
+			n, err := r.Read(b)
+			if n > 0 {
+				digester.Hash().Write(b[:n])
+				stream.XORKeyStream(b[:n], b[:n])
+				mac.Write(b[:n])
+				if _, err := w.Write(b[:n]); err != nil {
+					done <- fmt.Errorf("EncryptWriter: error writing to underlying writer: %v", err)
+					_, _ = io.Copy(ioutil.Discard, r)
+					return
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				done <- fmt.Errorf("EncryptWriter data read error: %v", err)
+				return
+			}
+		}
+		pubOpts, err := json.Marshal(publicOptions{
+			Cipher:        CipherAES256CTR,
+			HMAC:          mac.Sum(nil),
+			CipherOptions: map[string][]byte{},
+		})
+		if err != nil {
+			done <- errors.Wrap(err, "EncryptWriter: error encoding public options")
+			return
+		}
+		privOpts, err := json.Marshal(privateOptions{
+			SymmetricKey:  key,
+			Digest:        digester.Digest(),
+			CipherOptions: map[string][]byte{nonceOption: nonce},
+		})
+		if err != nil {
+			done <- errors.Wrap(err, "EncryptWriter: error encoding private options")
+			return
+		}
+		wrapped, err := wrapOptions(privOpts, recipients)
+		if err != nil {
+			done <- errors.Wrap(err, "EncryptWriter: error wrapping private options")
+			return
+		}
+		annotations[AnnotationEncryptionKeysJWE] = wrapped
+		annotations[AnnotationEncryptionPubOpts] = base64.StdEncoding.EncodeToString(pubOpts)
+		done <- nil
+	}, opts...), annotations, nil
+}
+
+// NewDecryptWriter wrap a writer with a decryption, so that the stream
+// encrypted in the layout of ocicrypt is decrypted before being passed
+// through. The private options of the layer are unwrapped from the JWE of the
+// annotations of its descriptor with one of the private keys. As the HMAC and
+// the digest cover the whole stream, the decrypted stream is buffered in a
+// temporary file, and only passed through once both are verified: a stream
+// which was tampered with is detected when committing, with ErrMACMismatch,
+// and nothing is written to the writer.
+//
+// By default, it calculates the hash when writing. If the option `skipHash` is true,
+// it will skip doing the hash. Skipping the hash is intended to be used only
+// if you are confident about the validity of the data being passed to the writer,
+// and wish to save on the hashing time.
+func NewDecryptWriter(writer content.Writer, desc ocispec.Descriptor, keys []*rsa.PrivateKey, opts ...WriterOpt) (content.Writer, error) {
+	// process opts for default
+	wOpts := DefaultWriterOpts()
+	for _, opt := range opts {
+		if err := opt(&wOpts); err != nil {
+			return nil, err
+		}
+	}
+	pubOpts, err := parsePublicOptions(desc)
+	if err != nil {
+		return nil, err
+	}
+	privOpts, err := unwrapOptions(desc, keys)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := newStream(privOpts.SymmetricKey, privOpts.CipherOptions[nonceOption])
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: invalid private options", desc.Digest)
+	}
+	if err := privOpts.Digest.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "%s: invalid private options", desc.Digest)
+	}
+	return NewPassthroughWriter(writer, func(r io.Reader, w io.Writer, done chan<- error) {
+		buffer, err := ioutil.TempFile("", TempFilePattern)
+		if err != nil {
+			done <- errors.Wrap(err, "DecryptWriter: error creating buffer")
+			_, _ = io.Copy(ioutil.Discard, r)
+			return
+		}
+		defer func() {
+			buffer.Close()
+			os.Remove(buffer.Name())
+		}()
+
+		mac := hmac.New(sha256.New, privOpts.SymmetricKey)
+		verifier := privOpts.Digest.Verifier()
+		b := make([]byte, wOpts.Blocksize)
+		for {
+			n, err := r.Read(b)
+			if n > 0 {
+				mac.Write(b[:n])
+				stream.XORKeyStream(b[:n], b[:n])
+				verifier.Write(b[:n])
+				if _, err := buffer.Write(b[:n]); err != nil {
+					done <- errors.Wrap(err, "DecryptWriter: error writing to buffer")
+					_, _ = io.Copy(ioutil.Discard, r)
+					return
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				done <- errors.Wrap(err, "DecryptWriter data read error")
+				return
+			}
+		}
+		if !hmac.Equal(pubOpts.HMAC, mac.Sum(nil)) {
+			done <- errors.Wrap(ErrMACMismatch, desc.Digest.String())
+			return
+		}
+		if !verifier.Verified() {
+			done <- errors.Wrapf(ErrDigestMismatch, "%s: decrypted content is not %s", desc.Digest, privOpts.Digest)
+			return
+		}
+		if _, err := buffer.Seek(0, io.SeekStart); err != nil {
+			done <- errors.Wrap(err, "DecryptWriter: error reading buffer")
+			return
+		}
+		if _, err := io.CopyBuffer(w, buffer, b); err != nil {
+			done <- errors.Wrap(err, "DecryptWriter: error writing to underlying writer")
+			return
+		}
+		done <- nil
+	}, opts...), nil
+}
+
+// newStream returns the AES-CTR stream of the key and nonce
+func newStream(key, nonce []byte) (cipher.Stream, error) {
+	if len(key) != keySize {
+		return nil, errors.Errorf("invalid key size %d", len(key))
+	}
+	if len(nonce) != aes.BlockSize {
+		return nil, errors.Errorf("invalid nonce size %d", len(nonce))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(block, nonce), nil
+}
+
+// wrapOptions wraps the private options in a JWE for all the recipients, and
+// returns it base64 encoded
+func wrapOptions(privOpts []byte, recipients []*rsa.PublicKey) (string, error) {
+	joseRecipients := make([]jose.Recipient, 0, len(recipients))
+	for _, recipient := range recipients {
+		joseRecipients = append(joseRecipients, jose.Recipient{
+			Algorithm: jose.RSA_OAEP,
+			Key:       recipient,
+		})
+	}
+	encrypter, err := jose.NewMultiEncrypter(jose.A256GCM, joseRecipients, nil)
+	if err != nil {
+		return "", err
+	}
+	jwe, err := encrypter.Encrypt(privOpts)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(jwe.FullSerialize())), nil
+}
+
+// parsePublicOptions parses the public options of an encrypted layer from the
+// annotations of its descriptor
+func parsePublicOptions(desc ocispec.Descriptor) (*publicOptions, error) {
+	annotation, ok := desc.Annotations[AnnotationEncryptionPubOpts]
+	if !ok {
+		return nil, errors.Errorf("%s: no %s annotation", desc.Digest, AnnotationEncryptionPubOpts)
+	}
+	b, err := base64.StdEncoding.DecodeString(annotation)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: invalid %s annotation", desc.Digest, AnnotationEncryptionPubOpts)
+	}
+	var pubOpts publicOptions
+	if err := json.Unmarshal(b, &pubOpts); err != nil {
+		return nil, errors.Wrapf(err, "%s: invalid %s annotation", desc.Digest, AnnotationEncryptionPubOpts)
+	}
+	if pubOpts.Cipher != CipherAES256CTR {
+		return nil, errors.Wrapf(ErrUnsupported, "%s: cipher %q", desc.Digest, pubOpts.Cipher)
+	}
+	return &pubOpts, nil
+}
+
+// unwrapOptions unwraps the private options of an encrypted layer from the JWEs
+// of the annotations of its descriptor with one of the private keys. The keys
+// wrapped with the other schemes of ocicrypt, such as PKCS7, are ignored.
+func unwrapOptions(desc ocispec.Descriptor, keys []*rsa.PrivateKey) (*privateOptions, error) {
+	annotation, ok := desc.Annotations[AnnotationEncryptionKeysJWE]
+	if !ok {
+		return nil, errors.Wrapf(ErrNoDecryptionKey, "%s: no %s annotation", desc.Digest, AnnotationEncryptionKeysJWE)
+	}
+	for _, encoded := range strings.Split(annotation, ",") {
+		b, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: invalid %s annotation", desc.Digest, AnnotationEncryptionKeysJWE)
+		}
+		jwe, err := jose.ParseEncrypted(string(b))
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: invalid %s annotation", desc.Digest, AnnotationEncryptionKeysJWE)
+		}
+		for _, key := range keys {
+			_, _, p, err := jwe.DecryptMulti(key)
+			if err != nil {
+				continue
+			}
+			var privOpts privateOptions
+			if err := json.Unmarshal(p, &privOpts); err != nil {
+				return nil, errors.Wrapf(err, "%s: invalid private options", desc.Digest)
+			}
+			return &privOpts, nil
+		}
+	}
+	return nil, errors.Wrap(ErrNoDecryptionKey, desc.Digest.String())
+}
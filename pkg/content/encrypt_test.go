@@ -0,0 +1,203 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package content_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	ctrcontent "github.com/containerd/containerd/content"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/content"
+)
+
+// decrypt pushes the encrypted content through a Decompress store with the
+// keys, and returns the content stored
+func decrypt(t *testing.T, desc ocispec.Descriptor, encrypted []byte, keys ...*rsa.PrivateKey) ([]byte, error) {
+	ctx := context.Background()
+	memStore := content.NewMemory()
+	memPusher, _ := memStore.Pusher(ctx, "")
+	decompressStore := content.NewDecompress(memPusher, content.WithDecryptionKeys(keys...))
+	w, err := decompressStore.Push(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(encrypted); err != nil {
+		return nil, err
+	}
+	if err := w.Commit(ctx, desc.Size, desc.Digest); err != nil {
+		return nil, err
+	}
+	_, b, found := memStore.Get(desc)
+	if !found {
+		t.Fatal("failed to get data from underlying memory store")
+	}
+	return b, nil
+}
+
+// countingWriter counts the bytes written to a writer
+type countingWriter struct {
+	ctrcontent.Writer
+	written int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.written += len(p)
+	return w.Writer.Write(p)
+}
+
+func TestEncryptWriter(t *testing.T) {
+	ctx := context.Background()
+	var keys []*rsa.PrivateKey
+	for i := 0; i < 3; i++ {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("unable to generate key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	recipients := []*rsa.PublicKey{&keys[0].PublicKey, &keys[1].PublicKey}
+	stranger := keys[2]
+
+	if _, _, err := content.NewEncryptWriter(content.NewIoContentWriter(nil), nil); err == nil {
+		t.Fatal("expected error without recipient")
+	}
+
+	var gzipBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzipBuf)
+	if _, err := gw.Write(testContent); err != nil {
+		t.Fatalf("unable to create gzip content for testing: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("unable to close gzip writer creating content for testing: %v", err)
+	}
+
+	tests := []struct {
+		mediaType string
+		input     []byte
+	}{
+		{ocispec.MediaTypeImageConfig, testContent},
+		{ocispec.MediaTypeImageConfig + "+gzip", gzipBuf.Bytes()},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w, annotations, err := content.NewEncryptWriter(content.NewIoContentWriter(&buf), recipients)
+		if err != nil {
+			t.Fatalf("%s: unexpected error creating encrypt writer: %v", tt.mediaType, err)
+		}
+		if err := ctrcontent.Copy(ctx, w, bytes.NewReader(tt.input), int64(len(tt.input)), digest.FromBytes(tt.input)); err != nil {
+			t.Fatalf("%s: unexpected error encrypting: %v", tt.mediaType, err)
+		}
+		encrypted := buf.Bytes()
+		if bytes.Contains(encrypted, tt.input) {
+			t.Errorf("%s: content not encrypted", tt.mediaType)
+		}
+		if len(encrypted) != len(tt.input) {
+			t.Errorf("%s: encrypted size %d, expected %d", tt.mediaType, len(encrypted), len(tt.input))
+		}
+		if _, ok := annotations[content.AnnotationEncryptionKeysJWE]; !ok {
+			t.Errorf("%s: no %s annotation", tt.mediaType, content.AnnotationEncryptionKeysJWE)
+		}
+		var pubOpts struct {
+			Cipher string `json:"cipher"`
+			HMAC   []byte `json:"hmac"`
+		}
+		b, err := base64.StdEncoding.DecodeString(annotations[content.AnnotationEncryptionPubOpts])
+		if err != nil {
+			t.Fatalf("%s: invalid %s annotation: %v", tt.mediaType, content.AnnotationEncryptionPubOpts, err)
+		}
+		if err := json.Unmarshal(b, &pubOpts); err != nil {
+			t.Fatalf("%s: invalid %s annotation: %v", tt.mediaType, content.AnnotationEncryptionPubOpts, err)
+		}
+		if pubOpts.Cipher != content.CipherAES256CTR || len(pubOpts.HMAC) != sha256.Size {
+			t.Errorf("%s: unexpected public options %s", tt.mediaType, b)
+		}
+		desc := ocispec.Descriptor{
+			MediaType:   content.EncryptedMediaType(tt.mediaType),
+			Digest:      digest.FromBytes(encrypted),
+			Size:        int64(len(encrypted)),
+			Annotations: annotations,
+		}
+
+		// each recipient can decrypt
+		for _, key := range keys[:2] {
+			output, err := decrypt(t, desc, encrypted, key)
+			if err != nil {
+				t.Fatalf("%s: unexpected error decrypting: %v", tt.mediaType, err)
+			}
+			if !bytes.Equal(output, testContent) {
+				t.Errorf("%s: mismatched content %q", tt.mediaType, output)
+			}
+		}
+		if _, err := decrypt(t, desc, encrypted); !errors.Is(err, content.ErrNoDecryptionKey) {
+			t.Errorf("%s: no key error = %v, expected %v", tt.mediaType, err, content.ErrNoDecryptionKey)
+		}
+		if _, err := decrypt(t, desc, encrypted, stranger); !errors.Is(err, content.ErrNoDecryptionKey) {
+			t.Errorf("%s: stranger key error = %v, expected %v", tt.mediaType, err, content.ErrNoDecryptionKey)
+		}
+		if !strings.HasSuffix(desc.MediaType, "+encrypted") {
+			t.Errorf("%s: encrypted media type %s", tt.mediaType, desc.MediaType)
+		}
+	}
+
+	// tampering with the encrypted content is detected
+	input := bytes.Repeat(testContent, 100)
+	var buf bytes.Buffer
+	w, annotations, err := content.NewEncryptWriter(content.NewIoContentWriter(&buf), recipients)
+	if err != nil {
+		t.Fatalf("unexpected error creating encrypt writer: %v", err)
+	}
+	if err := ctrcontent.Copy(ctx, w, bytes.NewReader(input), int64(len(input)), digest.FromBytes(input)); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+	for _, tampered := range [][]byte{
+		append([]byte{buf.Bytes()[0] ^ 1}, buf.Bytes()[1:]...),
+		buf.Bytes()[:buf.Len()-1],
+	} {
+		desc := ocispec.Descriptor{
+			MediaType:   content.EncryptedMediaType(ocispec.MediaTypeImageConfig),
+			Digest:      digest.FromBytes(tampered),
+			Size:        int64(len(tampered)),
+			Annotations: annotations,
+		}
+		if _, err := decrypt(t, desc, tampered, keys[0]); !errors.Is(err, content.ErrMACMismatch) {
+			t.Errorf("tampered content error = %v, expected %v", err, content.ErrMACMismatch)
+		}
+
+		// nothing is passed through before the HMAC is verified
+		output := &countingWriter{Writer: content.NewIoContentWriter(nil)}
+		w, err := content.NewDecryptWriter(output, desc, keys[:1])
+		if err != nil {
+			t.Fatalf("unexpected error creating decrypt writer: %v", err)
+		}
+		if err := ctrcontent.Copy(ctx, w, bytes.NewReader(tampered), desc.Size, desc.Digest); !errors.Is(err, content.ErrMACMismatch) {
+			t.Errorf("tampered content error = %v, expected %v", err, content.ErrMACMismatch)
+		}
+		if output.written != 0 {
+			t.Errorf("%d bytes of tampered content passed through", output.written)
+		}
+	}
+}
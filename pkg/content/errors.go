@@ -23,6 +23,12 @@ var (
 	ErrNoName             = errors.New("no_name")
 	ErrUnsupportedSize    = errors.New("unsupported_size")
 	ErrUnsupportedVersion = errors.New("unsupported_version")
+	ErrUnsupported        = errors.New("unsupported")
+	ErrNoArtifactType     = errors.New("no_artifact_type")
+	ErrDigestMismatch     = errors.New("digest_mismatch")
+	ErrSizeMismatch       = errors.New("size_mismatch")
+	ErrNoDecryptionKey    = errors.New("no_decryption_key")
+	ErrMACMismatch        = errors.New("mac_mismatch")
 )
 
 // FileStore errors
@@ -0,0 +1,78 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content
+
+import (
+	"sort"
+	"strings"
+)
+
+// splitReference splits a reference name into its repository and its tag.
+// Names without a repository, such as the plain tags used in OCI layouts, have
+// an empty repository. Digest references have an empty tag.
+func splitReference(name string) (repository, tag string) {
+	if i := strings.Index(name, "@"); i >= 0 {
+		return name[:i], ""
+	}
+	slash := strings.LastIndex(name, "/")
+	if slash < 0 {
+		return "", name
+	}
+	if colon := strings.LastIndex(name, ":"); colon > slash {
+		return name[:colon], name[colon+1:]
+	}
+	return name, ""
+}
+
+// listTags returns the sorted tags of the repository from the reference names
+func listTags(names []string, repository string) []string {
+	repository, _ = splitReference(repository)
+	set := make(map[string]bool)
+	for _, name := range names {
+		if repo, tag := splitReference(name); tag != "" && repo == repository {
+			set[tag] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+// listRepositories returns the sorted repositories of the host from the
+// reference names, without the host. If the host is empty, the full names of
+// all repositories are returned.
+func listRepositories(names []string, host string) []string {
+	set := make(map[string]bool)
+	for _, name := range names {
+		repo, _ := splitReference(name)
+		if repo == "" {
+			continue
+		}
+		if host == "" {
+			set[repo] = true
+		} else if strings.HasPrefix(repo, host+"/") {
+			set[strings.TrimPrefix(repo, host+"/")] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
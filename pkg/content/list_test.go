@@ -0,0 +1,92 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/target"
+)
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "oras_list_test")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ociStore, err := content.NewOCI(dir)
+	if err != nil {
+		t.Fatalf("unable to create OCI store: %v", err)
+	}
+	memoryStore := content.NewMemory()
+
+	names := []string{
+		"localhost:5000/team/a:v2",
+		"localhost:5000/team/a:v1",
+		"localhost:5000/team/a@sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0",
+		"localhost:5000/team/b:latest",
+		"example.com/c:v1",
+		"plain",
+	}
+	for _, name := range names {
+		manifest := []byte(name)
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(manifest),
+			Size:      int64(len(manifest)),
+		}
+		if err := memoryStore.StoreManifest(name, desc, manifest); err != nil {
+			t.Fatalf("unable to store %s: %v", name, err)
+		}
+		ociStore.AddReference(name, desc)
+	}
+
+	for _, lister := range []target.Lister{memoryStore, ociStore} {
+		tags, err := lister.Tags(ctx, "localhost:5000/team/a:v9")
+		if err != nil {
+			t.Fatalf("unable to list tags: %v", err)
+		}
+		if expected := []string{"v1", "v2"}; !reflect.DeepEqual(tags, expected) {
+			t.Fatalf("listed tags %v, expected %v", tags, expected)
+		}
+
+		tags, err = lister.Tags(ctx, "")
+		if err != nil {
+			t.Fatalf("unable to list tags: %v", err)
+		}
+		if expected := []string{"plain"}; !reflect.DeepEqual(tags, expected) {
+			t.Fatalf("listed tags %v, expected %v", tags, expected)
+		}
+
+		repositories, err := lister.Repositories(ctx, "localhost:5000")
+		if err != nil {
+			t.Fatalf("unable to list repositories: %v", err)
+		}
+		if expected := []string{"team/a", "team/b"}; !reflect.DeepEqual(repositories, expected) {
+			t.Fatalf("listed repositories %v, expected %v", repositories, expected)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content
+
+import (
+	"container/list"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// lruCache tracks the recency of use and the size of content by digest, so that
+// the least recently used content can be evicted first.
+// It is not safe for concurrent use.
+type lruCache struct {
+	list  *list.List
+	items map[digest.Digest]*list.Element
+	size  int64
+}
+
+type lruEntry struct {
+	digest digest.Digest
+	size   int64
+}
+
+func newLRUCache() *lruCache {
+	return &lruCache{
+		list:  list.New(),
+		items: make(map[digest.Digest]*list.Element),
+	}
+}
+
+// add adds the digest as the most recently used, replacing any previous entry.
+func (c *lruCache) add(dgst digest.Digest, size int64) {
+	c.remove(dgst)
+	c.items[dgst] = c.list.PushFront(&lruEntry{
+		digest: dgst,
+		size:   size,
+	})
+	c.size += size
+}
+
+// touch marks the digest as the most recently used. It returns false if the
+// digest is unknown.
+func (c *lruCache) touch(dgst digest.Digest) bool {
+	elem, ok := c.items[dgst]
+	if !ok {
+		return false
+	}
+	c.list.MoveToFront(elem)
+	return true
+}
+
+// remove removes the digest. It returns false if the digest is unknown.
+func (c *lruCache) remove(dgst digest.Digest) bool {
+	elem, ok := c.items[dgst]
+	if !ok {
+		return false
+	}
+	c.list.Remove(elem)
+	delete(c.items, dgst)
+	c.size -= elem.Value.(*lruEntry).size
+	return true
+}
+
+// evict removes the least recently used digests until the total size is within
+// limit, and returns them. Digests for which keep returns true are never evicted,
+// so the total size may still exceed the limit afterwards.
+func (c *lruCache) evict(limit int64, keep func(digest.Digest) bool) []digest.Digest {
+	var evicted []digest.Digest
+	elem := c.list.Back()
+	for elem != nil && c.size > limit {
+		prev := elem.Prev()
+		entry := elem.Value.(*lruEntry)
+		if keep == nil || !keep(entry.digest) {
+			c.remove(entry.digest)
+			evicted = append(evicted, entry.digest)
+		}
+		elem = prev
+	}
+	return evicted
+}
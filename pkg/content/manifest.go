@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"sort"
 
+	"github.com/containerd/containerd/images"
 	"github.com/opencontainers/go-digest"
 	specs "github.com/opencontainers/image-spec/specs-go"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -35,7 +36,58 @@ func GenerateManifest(config *ocispec.Descriptor, annotations map[string]string,
 		}
 		config = &configGen
 	}
-	return pack(*config, annotations, descs)
+	return pack(*config, annotations, descs, &manifestOpts{})
+}
+
+// ManifestOpt configures the generation of a manifest
+type ManifestOpt func(*manifestOpts) error
+
+type manifestOpts struct {
+	subject      *ocispec.Descriptor
+	artifactType string
+}
+
+// WithSubject sets the subject of the manifest, which is the manifest that the
+// generated one refers to, such as the image a signature is attached to.
+func WithSubject(subject ocispec.Descriptor) ManifestOpt {
+	return func(o *manifestOpts) error {
+		if err := subject.Digest.Validate(); err != nil {
+			return err
+		}
+		o.subject = &subject
+		return nil
+	}
+}
+
+// WithArtifactType sets the artifact type of the manifest
+func WithArtifactType(artifactType string) ManifestOpt {
+	return func(o *manifestOpts) error {
+		o.artifactType = artifactType
+		return nil
+	}
+}
+
+// GenerateManifestWithOpts generates a manifest like GenerateManifest, with the
+// options. If config is nil and an artifact type is set, the config is the
+// empty JSON object of GenerateEmptyConfig.
+func GenerateManifestWithOpts(config *ocispec.Descriptor, annotations map[string]string, descs []ocispec.Descriptor, opts ...ManifestOpt) ([]byte, ocispec.Descriptor, error) {
+	o := &manifestOpts{}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, ocispec.Descriptor{}, err
+		}
+	}
+	if config == nil {
+		_, configGen, err := GenerateConfig(nil)
+		if o.artifactType != "" {
+			_, configGen, err = GenerateEmptyConfig()
+		}
+		if err != nil {
+			return nil, ocispec.Descriptor{}, err
+		}
+		config = &configGen
+	}
+	return pack(*config, annotations, descs, o)
 }
 
 // GenerateConfig generates a blank config with optional annotations.
@@ -51,6 +103,55 @@ func GenerateConfig(annotations map[string]string) ([]byte, ocispec.Descriptor,
 	return configBytes, config, nil
 }
 
+// GenerateArtifactManifest generates an OCI artifact manifest of the artifact
+// type, with descs as blobs. Only the WithSubject option applies, as the
+// artifact type is always set. Raw bytes will be returned.
+func GenerateArtifactManifest(artifactType string, annotations map[string]string, descs []ocispec.Descriptor, opts ...ManifestOpt) ([]byte, ocispec.Descriptor, error) {
+	if artifactType == "" {
+		return nil, ocispec.Descriptor{}, ErrNoArtifactType
+	}
+	o := &manifestOpts{}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, ocispec.Descriptor{}, err
+		}
+	}
+	// sort descriptors alphanumerically by sha hash so it always is consistent
+	descs = append([]ocispec.Descriptor(nil), descs...)
+	sort.Slice(descs, func(i, j int) bool {
+		return descs[i].Digest < descs[j].Digest
+	})
+	manifest := artifact.Manifest{
+		MediaType:    artifact.MediaTypeArtifactManifest,
+		ArtifactType: artifactType,
+		Blobs:        descs,
+		Subject:      o.subject,
+		Annotations:  annotations,
+	}
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, ocispec.Descriptor{}, err
+	}
+	manifestDescriptor := ocispec.Descriptor{
+		MediaType: artifact.MediaTypeArtifactManifest,
+		Digest:    digest.FromBytes(manifestBytes),
+		Size:      int64(len(manifestBytes)),
+	}
+	return manifestBytes, manifestDescriptor, nil
+}
+
+// GenerateEmptyConfig generates the empty JSON object config, for artifacts
+// with an artifact type and no config.
+func GenerateEmptyConfig() ([]byte, ocispec.Descriptor, error) {
+	configBytes := []byte("{}")
+	config := ocispec.Descriptor{
+		MediaType: artifact.MediaTypeEmptyJSON,
+		Digest:    digest.FromBytes(configBytes),
+		Size:      int64(len(configBytes)),
+	}
+	return configBytes, config, nil
+}
+
 // GenerateManifestAndConfig generates a config and then a manifest. Raw bytes will be returned.
 func GenerateManifestAndConfig(manifestAnnotations map[string]string, configAnnotations map[string]string, descs ...ocispec.Descriptor) (manifest []byte, manifestDesc ocispec.Descriptor, config []byte, configDesc ocispec.Descriptor, err error) {
 	config, configDesc, err = GenerateConfig(configAnnotations)
@@ -65,7 +166,7 @@ func GenerateManifestAndConfig(manifestAnnotations map[string]string, configAnno
 }
 
 // pack given a bunch of descriptors, create a manifest that references all of them
-func pack(config ocispec.Descriptor, annotations map[string]string, descriptors []ocispec.Descriptor) ([]byte, ocispec.Descriptor, error) {
+func pack(config ocispec.Descriptor, annotations map[string]string, descriptors []ocispec.Descriptor, opts *manifestOpts) ([]byte, ocispec.Descriptor, error) {
 	if descriptors == nil {
 		descriptors = []ocispec.Descriptor{} // make it an empty array to prevent potential server-side bugs
 	}
@@ -73,13 +174,20 @@ func pack(config ocispec.Descriptor, annotations map[string]string, descriptors
 	sort.Slice(descriptors, func(i, j int) bool {
 		return descriptors[i].Digest < descriptors[j].Digest
 	})
-	manifest := ocispec.Manifest{
+	manifest := artifact.ImageManifest{
 		Versioned: specs.Versioned{
 			SchemaVersion: 2, // historical value. does not pertain to OCI or docker version
 		},
-		Config:      config,
-		Layers:      descriptors,
-		Annotations: annotations,
+		ArtifactType: opts.artifactType,
+		Config:       config,
+		Layers:       descriptors,
+		Subject:      opts.subject,
+		Annotations:  annotations,
+	}
+	// the media type is required for the manifest to be found as a referrer,
+	// and left out otherwise to keep generating the same manifests
+	if opts.subject != nil || opts.artifactType != "" {
+		manifest.MediaType = ocispec.MediaTypeImageManifest
 	}
 	manifestBytes, err := json.Marshal(manifest)
 	if err != nil {
@@ -93,3 +201,55 @@ func pack(config ocispec.Descriptor, annotations map[string]string, descriptors
 
 	return manifestBytes, manifestDescriptor, nil
 }
+
+// manifestMediaTypes are the media types of the manifests and indexes
+var manifestMediaTypes = []string{
+	ocispec.MediaTypeImageManifest,
+	ocispec.MediaTypeImageIndex,
+	artifact.MediaTypeArtifactManifest,
+	images.MediaTypeDockerSchema2Manifest,
+	images.MediaTypeDockerSchema2ManifestList,
+}
+
+// ManifestMediaTypes returns the media types of the manifests and indexes,
+// in either the OCI, the artifact or the Docker schema2 media types.
+func ManifestMediaTypes() []string {
+	return append([]string(nil), manifestMediaTypes...)
+}
+
+// IsManifestMediaType reports whether the media type is one of a manifest or an index
+func IsManifestMediaType(mediaType string) bool {
+	for _, mt := range manifestMediaTypes {
+		if mt == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// manifestChildren returns the descriptors referenced by a manifest or an index,
+// in either the OCI or the Docker schema2 media types.
+// Other media types have no children.
+func manifestChildren(mediaType string, content []byte) ([]ocispec.Descriptor, error) {
+	switch mediaType {
+	case ocispec.MediaTypeImageManifest, images.MediaTypeDockerSchema2Manifest:
+		var manifest ocispec.Manifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, err
+		}
+		return append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...), nil
+	case ocispec.MediaTypeImageIndex, images.MediaTypeDockerSchema2ManifestList:
+		var index ocispec.Index
+		if err := json.Unmarshal(content, &index); err != nil {
+			return nil, err
+		}
+		return index.Manifests, nil
+	case artifact.MediaTypeArtifactManifest:
+		var manifest artifact.Manifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, err
+		}
+		return manifest.Blobs, nil
+	}
+	return nil, nil
+}
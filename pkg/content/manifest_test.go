@@ -0,0 +1,144 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/artifact"
+	"oras.land/oras-go/pkg/content"
+)
+
+func TestGenerateManifestWithOpts(t *testing.T) {
+	layer := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    "sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0",
+		Size:      3,
+	}
+	annotations := map[string]string{"key": "value"}
+
+	// without options, the manifest is the one of GenerateManifest
+	expected, _, err := content.GenerateManifest(nil, annotations, layer)
+	if err != nil {
+		t.Fatalf("unable to generate manifest: %v", err)
+	}
+	manifest, _, err := content.GenerateManifestWithOpts(nil, annotations, []ocispec.Descriptor{layer})
+	if err != nil {
+		t.Fatalf("unable to generate manifest: %v", err)
+	}
+	if !bytes.Equal(manifest, expected) {
+		t.Fatalf("generated %s, expected %s", manifest, expected)
+	}
+
+	_, subject, err := content.GenerateManifest(nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate subject: %v", err)
+	}
+	manifest, desc, err := content.GenerateManifestWithOpts(nil, annotations, []ocispec.Descriptor{layer},
+		content.WithSubject(subject),
+		content.WithArtifactType("application/vnd.example.signature"),
+	)
+	if err != nil {
+		t.Fatalf("unable to generate referrer: %v", err)
+	}
+	if desc.MediaType != ocispec.MediaTypeImageManifest {
+		t.Fatalf("unexpected media type %s", desc.MediaType)
+	}
+	var referrer artifact.ImageManifest
+	if err := json.Unmarshal(manifest, &referrer); err != nil {
+		t.Fatalf("invalid referrer: %v", err)
+	}
+	if referrer.MediaType != ocispec.MediaTypeImageManifest {
+		t.Fatalf("expected the media type in the manifest, got %q", referrer.MediaType)
+	}
+	if referrer.ArtifactType != "application/vnd.example.signature" {
+		t.Fatalf("unexpected artifact type %q", referrer.ArtifactType)
+	}
+	if referrer.Subject == nil || referrer.Subject.Digest != subject.Digest {
+		t.Fatalf("unexpected subject %v", referrer.Subject)
+	}
+	_, emptyConfig, err := content.GenerateEmptyConfig()
+	if err != nil {
+		t.Fatalf("unable to generate empty config: %v", err)
+	}
+	if referrer.Config.MediaType != artifact.MediaTypeEmptyJSON || referrer.Config.Digest != emptyConfig.Digest {
+		t.Fatalf("expected the empty config, got %v", referrer.Config)
+	}
+
+	if _, _, err := content.GenerateManifestWithOpts(nil, nil, nil, content.WithSubject(ocispec.Descriptor{})); err == nil {
+		t.Fatalf("expected an error for an invalid subject")
+	}
+}
+
+func TestGenerateArtifactManifest(t *testing.T) {
+	blob := ocispec.Descriptor{
+		MediaType: "application/vnd.example.sbom.v1+json",
+		Digest:    "sha256:9834876dcfb05cb167a5c24953eba58c4ac89b1adf57f28f2f9d09af107ee8f0",
+		Size:      3,
+	}
+	_, subject, err := content.GenerateManifest(nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate subject: %v", err)
+	}
+
+	if _, _, err := content.GenerateArtifactManifest("", nil, nil); !errors.Is(err, content.ErrNoArtifactType) {
+		t.Fatalf("expected no artifact type error, got %v", err)
+	}
+	manifest, desc, err := content.GenerateArtifactManifest("application/vnd.example.sbom", nil, []ocispec.Descriptor{blob}, content.WithSubject(subject))
+	if err != nil {
+		t.Fatalf("unable to generate artifact manifest: %v", err)
+	}
+	if desc.MediaType != artifact.MediaTypeArtifactManifest {
+		t.Fatalf("unexpected media type %s", desc.MediaType)
+	}
+	var generated artifact.Manifest
+	if err := json.Unmarshal(manifest, &generated); err != nil {
+		t.Fatalf("invalid artifact manifest: %v", err)
+	}
+	if generated.MediaType != artifact.MediaTypeArtifactManifest || generated.ArtifactType != "application/vnd.example.sbom" {
+		t.Fatalf("unexpected artifact manifest %s", manifest)
+	}
+	if len(generated.Blobs) != 1 || generated.Blobs[0].Digest != blob.Digest {
+		t.Fatalf("unexpected blobs %v", generated.Blobs)
+	}
+	if generated.Subject == nil || generated.Subject.Digest != subject.Digest {
+		t.Fatalf("unexpected subject %v", generated.Subject)
+	}
+}
+
+func TestIsManifestMediaType(t *testing.T) {
+	mediaTypes := content.ManifestMediaTypes()
+	if len(mediaTypes) != 5 {
+		t.Fatalf("unexpected manifest media types %v", mediaTypes)
+	}
+	for _, mediaType := range mediaTypes {
+		if !content.IsManifestMediaType(mediaType) {
+			t.Errorf("expected %s to be a manifest media type", mediaType)
+		}
+	}
+	mediaTypes[0] = ocispec.MediaTypeImageLayer
+	if content.IsManifestMediaType(ocispec.MediaTypeImageLayer) {
+		t.Fatalf("expected %s not to be a manifest media type", ocispec.MediaTypeImageLayer)
+	}
+	if !content.IsManifestMediaType(ocispec.MediaTypeImageManifest) {
+		t.Fatalf("expected the manifest media types not to be modified through the returned slice")
+	}
+}
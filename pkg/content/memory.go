@@ -31,6 +31,8 @@ import (
 	digest "github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
+
+	"oras.land/oras-go/pkg/artifact"
 )
 
 // Memory provides content from the memory
@@ -40,24 +42,82 @@ type Memory struct {
 	nameMap    map[string]ocispec.Descriptor
 	refMap     map[string]ocispec.Descriptor
 	lock       *sync.Mutex
+
+	lru     *lruCache
+	limit   int64
+	onEvict func(ocispec.Descriptor)
+	stats   MemoryStats
+}
+
+// MemoryOpt configures a Memory store
+type MemoryOpt func(*Memory)
+
+// MemoryStats reports the usage of a Memory store
+type MemoryStats struct {
+	// Hits is the number of lookups that found the content
+	Hits int64
+	// Misses is the number of lookups that did not find the content
+	Misses int64
+	// Evictions is the number of blobs evicted to stay within the size limit
+	Evictions int64
+	// Size is the total size of the content in bytes
+	Size int64
 }
 
 // NewMemory creats a new memory store
-func NewMemory() *Memory {
-	return &Memory{
+func NewMemory(opts ...MemoryOpt) *Memory {
+	s := &Memory{
 		descriptor: make(map[digest.Digest]ocispec.Descriptor),
 		content:    make(map[digest.Digest][]byte),
 		nameMap:    make(map[string]ocispec.Descriptor),
 		refMap:     make(map[string]ocispec.Descriptor),
 		lock:       &sync.Mutex{},
+		lru:        newLRUCache(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithMemoryLimit sets the maximum total size in bytes of the content held by
+// a Memory store. When adding content exceeds the limit, the least recently used
+// blobs are evicted. Manifests referenced by a ref are pinned and never evicted.
+// The content being added is never evicted either: if it alone exceeds the
+// limit, it is kept and the store stays over its limit until later additions
+// evict it. A limit of 0 or less means no limit, which is the default.
+func WithMemoryLimit(limit int64) MemoryOpt {
+	return func(s *Memory) {
+		s.limit = limit
+	}
+}
+
+// WithEvictionCallback sets a func called with the descriptor of each blob
+// evicted from a Memory store.
+func WithEvictionCallback(onEvict func(desc ocispec.Descriptor)) MemoryOpt {
+	return func(s *Memory) {
+		s.onEvict = onEvict
 	}
 }
 
+// Stats returns the usage statistics of the store
+func (s *Memory) Stats() MemoryStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stats := s.stats
+	stats.Size = s.lru.size
+	return stats
+}
+
 func (s *Memory) Resolver() remotes.Resolver {
 	return s
 }
 
 func (s *Memory) Resolve(ctx context.Context, ref string) (name string, desc ocispec.Descriptor, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	desc, ok := s.refMap[ref]
 	if !ok {
 		return "", ocispec.Descriptor{}, fmt.Errorf("unknown reference: %s", ref)
@@ -66,12 +126,52 @@ func (s *Memory) Resolve(ctx context.Context, ref string) (name string, desc oci
 }
 
 func (s *Memory) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	if _, ok := s.refMap[ref]; !ok {
 		return nil, fmt.Errorf("unknown reference: %s", ref)
 	}
 	return s, nil
 }
 
+// Tags lists the tags of the repository referenced by ref. References without
+// a repository are listed with an empty ref.
+func (s *Memory) Tags(ctx context.Context, ref string) ([]string, error) {
+	return listTags(s.refNames(), ref), nil
+}
+
+// Repositories lists the repositories of the host
+func (s *Memory) Repositories(ctx context.Context, host string) ([]string, error) {
+	return listRepositories(s.refNames(), host), nil
+}
+
+func (s *Memory) refNames() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	names := make([]string, 0, len(s.refMap))
+	for name := range s.refMap {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Discover returns the manifests of the store referring to the subject. The
+// content of the store is shared by all the repositories, so ref is ignored.
+func (s *Memory) Discover(ctx context.Context, ref string, subject ocispec.Descriptor, artifactType string) ([]artifact.Descriptor, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var referrers []artifact.Descriptor
+	for dgst, desc := range s.descriptor {
+		if referrer, ok := referrerOf(desc, s.content[dgst], subject.Digest); ok {
+			referrers = append(referrers, referrer)
+		}
+	}
+	return sortReferrers(artifact.FilterReferrers(referrers, artifactType)), nil
+}
+
 // Fetch get an io.ReadCloser for the specific content
 func (s *Memory) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
 	_, content, ok := s.Get(desc)
@@ -82,33 +182,47 @@ func (s *Memory) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadClo
 }
 
 func (s *Memory) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
-	var tag, hash string
+	return s.pusher(ref, true), nil
+}
+
+// UntaggedPusher returns a pusher which does not tag the root with ref, such
+// as for the referrers
+func (s *Memory) UntaggedPusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	return s.pusher(ref, false), nil
+}
+
+// pusher returns a pusher for the ref of the form name@digest, tagging the
+// root with the name if asked and the name is not empty
+func (s *Memory) pusher(ref string, tag bool) *memoryPusher {
+	var name, hash string
 	parts := strings.SplitN(ref, "@", 2)
 	if len(parts) > 0 {
-		tag = parts[0]
+		name = parts[0]
 	}
 	if len(parts) > 1 {
 		hash = parts[1]
 	}
 	return &memoryPusher{
 		store: s,
-		ref:   tag,
+		ref:   name,
 		hash:  hash,
-	}, nil
+		tag:   tag && name != "",
+	}
 }
 
 type memoryPusher struct {
 	store *Memory
 	ref   string
 	hash  string
+	tag   bool
 }
 
 func (s *memoryPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
 	name, _ := ResolveName(desc)
 	now := time.Now()
 	// is this the root?
-	if desc.Digest.String() == s.hash {
-		s.store.refMap[s.ref] = desc
+	if s.tag && desc.Digest.String() == s.hash {
+		s.store.setRef(s.ref, desc)
 	}
 	return &memoryWriter{
 		store:    s.store,
@@ -151,14 +265,112 @@ func (s *Memory) Add(name, mediaType string, content []byte) (ocispec.Descriptor
 // Set adds the content to the store
 func (s *Memory) Set(desc ocispec.Descriptor, content []byte) {
 	s.lock.Lock()
-	defer s.lock.Unlock()
-
 	s.descriptor[desc.Digest] = desc
 	s.content[desc.Digest] = content
+	s.lru.add(desc.Digest, int64(len(content)))
 
 	if name, ok := ResolveName(desc); ok && name != "" {
 		s.nameMap[name] = desc
 	}
+	evicted := s.evict(desc.Digest)
+	s.lock.Unlock()
+
+	if s.onEvict != nil {
+		for _, desc := range evicted {
+			s.onEvict(desc)
+		}
+	}
+}
+
+// evict removes the least recently used content until the store is within its
+// limit, except the content being added and the pinned manifests, returning the
+// descriptors of the removed content. The caller must hold the lock.
+func (s *Memory) evict(adding digest.Digest) []ocispec.Descriptor {
+	if s.limit <= 0 {
+		return nil
+	}
+	pinned := make(map[digest.Digest]bool, len(s.refMap)+1)
+	pinned[adding] = true
+	for _, desc := range s.refMap {
+		pinned[desc.Digest] = true
+	}
+	var evicted []ocispec.Descriptor
+	for _, dgst := range s.lru.evict(s.limit, func(dgst digest.Digest) bool {
+		return pinned[dgst]
+	}) {
+		desc, _ := s.remove(dgst)
+		evicted = append(evicted, desc)
+	}
+	s.stats.Evictions += int64(len(evicted))
+	return evicted
+}
+
+// remove removes the content from the store, returning its descriptor. The
+// caller must hold the lock.
+func (s *Memory) remove(dgst digest.Digest) (ocispec.Descriptor, bool) {
+	desc, ok := s.descriptor[dgst]
+	if !ok {
+		return ocispec.Descriptor{}, false
+	}
+	delete(s.descriptor, dgst)
+	delete(s.content, dgst)
+	s.lru.remove(dgst)
+	if name, ok := ResolveName(desc); ok && s.nameMap[name].Digest == dgst {
+		delete(s.nameMap, name)
+	}
+	return desc, true
+}
+
+// DeleteManifest deletes the references to the manifest in the repository of
+// ref, and the manifest itself unless references of other repositories still
+// point to it
+func (s *Memory) DeleteManifest(ctx context.Context, ref string, dgst digest.Digest) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.descriptor[dgst]; !ok {
+		return errors.Wrapf(ErrNotFound, "manifest %s", dgst)
+	}
+	repository, _ := splitReference(ref)
+	referenced := false
+	for name, desc := range s.refMap {
+		if desc.Digest != dgst {
+			continue
+		}
+		if repo, _ := splitReference(name); repo == repository {
+			delete(s.refMap, name)
+		} else {
+			referenced = true
+		}
+	}
+	if !referenced {
+		s.remove(dgst)
+	}
+	return nil
+}
+
+// DeleteBlob deletes the blob. The content of the store is shared by all the
+// repositories, so ref is ignored.
+func (s *Memory) DeleteBlob(ctx context.Context, ref string, dgst digest.Digest) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.remove(dgst); !ok {
+		return errors.Wrapf(ErrNotFound, "blob %s", dgst)
+	}
+	return nil
+}
+
+// Untag removes the reference, keeping the manifest it references
+func (s *Memory) Untag(ctx context.Context, ref string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.refMap[ref]; !ok {
+		return errors.Wrapf(ErrNotFound, "reference %s", ref)
+	}
+	delete(s.refMap, ref)
+	return nil
 }
 
 // Get finds the content from the store
@@ -168,10 +380,10 @@ func (s *Memory) Get(desc ocispec.Descriptor) (ocispec.Descriptor, []byte, bool)
 
 	desc, ok := s.descriptor[desc.Digest]
 	if !ok {
+		s.stats.Misses++
 		return ocispec.Descriptor{}, nil, false
 	}
-	content, ok := s.content[desc.Digest]
-	return desc, content, ok
+	return s.get(desc)
 }
 
 // GetByName finds the content from the store by name (i.e. AnnotationTitle)
@@ -181,10 +393,31 @@ func (s *Memory) GetByName(name string) (ocispec.Descriptor, []byte, bool) {
 
 	desc, ok := s.nameMap[name]
 	if !ok {
+		s.stats.Misses++
 		return ocispec.Descriptor{}, nil, false
 	}
+	return s.get(desc)
+}
+
+// get returns the content of a known descriptor, marking it as recently used.
+// The caller must hold the lock.
+func (s *Memory) get(desc ocispec.Descriptor) (ocispec.Descriptor, []byte, bool) {
 	content, ok := s.content[desc.Digest]
-	return desc, content, ok
+	if !ok {
+		s.stats.Misses++
+		return desc, nil, false
+	}
+	s.stats.Hits++
+	s.lru.touch(desc.Digest)
+	return desc, content, true
+}
+
+// setRef links the ref to the descriptor
+func (s *Memory) setRef(ref string, desc ocispec.Descriptor) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.refMap[ref] = desc
 }
 
 // StoreManifest stores a manifest linked to by the provided ref. The children of the
@@ -194,7 +427,7 @@ func (s *Memory) GetByName(name string) (ocispec.Descriptor, []byte, bool) {
 //
 // StoreManifest does *not* validate their presence.
 func (s *Memory) StoreManifest(ref string, desc ocispec.Descriptor, manifest []byte) error {
-	s.refMap[ref] = desc
+	s.setRef(ref, desc)
 	s.Add("", desc.MediaType, manifest)
 	return nil
 }
@@ -0,0 +1,267 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// ExportOCI writes all the content of the store to an OCI image layout directory
+// at rootPath. Refs stored via StoreManifest are recorded in the index.json with
+// the ref name annotation.
+func (s *Memory) ExportOCI(rootPath string) error {
+	return s.writeOCILayout(func(name string, content []byte) error {
+		filePath := filepath.Join(rootPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filePath, content, 0644)
+	})
+}
+
+// ExportOCIArchive writes all the content of the store as a tar archive of an
+// OCI image layout, as ExportOCI does for a directory.
+func (s *Memory) ExportOCIArchive(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	if err := s.writeOCILayout(func(name string, content []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// ImportOCI loads the refs of an OCI image layout directory at rootPath, and all
+// the content they reference, into the store.
+func (s *Memory) ImportOCI(rootPath string) error {
+	return s.readOCILayout(func(name string) ([]byte, error) {
+		return ioutil.ReadFile(filepath.Join(rootPath, filepath.FromSlash(name)))
+	})
+}
+
+// ImportOCIArchive loads a tar archive of an OCI image layout into the store, as
+// ImportOCI does for a directory.
+func (s *Memory) ImportOCIArchive(r io.Reader) error {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		files[path.Clean(header.Name)] = content
+	}
+	return s.readOCILayout(func(name string) ([]byte, error) {
+		content, ok := files[name]
+		if !ok {
+			return nil, errors.Wrap(ErrNotFound, name)
+		}
+		return content, nil
+	})
+}
+
+// writeOCILayout writes a snapshot of the store as the files of an OCI image layout
+func (s *Memory) writeOCILayout(writeFile func(name string, content []byte) error) error {
+	s.lock.Lock()
+	descs := make([]ocispec.Descriptor, 0, len(s.descriptor))
+	contents := make(map[digest.Digest][]byte, len(s.content))
+	for dgst, desc := range s.descriptor {
+		descs = append(descs, desc)
+		contents[dgst] = s.content[dgst]
+	}
+	refs := make(map[string]ocispec.Descriptor, len(s.refMap))
+	for ref, desc := range s.refMap {
+		refs[ref] = desc
+	}
+	s.lock.Unlock()
+
+	layoutJSON, err := json.Marshal(ocispec.ImageLayout{
+		Version: ocispec.ImageLayoutVersion,
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeFile(ocispec.ImageLayoutFile, layoutJSON); err != nil {
+		return err
+	}
+
+	sort.Slice(descs, func(i, j int) bool {
+		return descs[i].Digest < descs[j].Digest
+	})
+	for _, desc := range descs {
+		if err := desc.Digest.Validate(); err != nil {
+			return err
+		}
+		if err := writeFile(blobPath(desc.Digest), contents[desc.Digest]); err != nil {
+			return err
+		}
+	}
+
+	index := ocispec.Index{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2, // historical value
+		},
+		Manifests: []ocispec.Descriptor{},
+	}
+	names := make([]string, 0, len(refs))
+	for ref := range refs {
+		names = append(names, ref)
+	}
+	sort.Strings(names)
+	for _, ref := range names {
+		desc := refs[ref]
+		annotations := map[string]string{
+			ocispec.AnnotationRefName: ref,
+		}
+		for k, v := range desc.Annotations {
+			annotations[k] = v
+		}
+		desc.Annotations = annotations
+		index.Manifests = append(index.Manifests, desc)
+	}
+	indexJSON, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return writeFile(OCIImageIndexFile, indexJSON)
+}
+
+// readOCILayout loads the refs of an OCI image layout, and the content they
+// reference, from the files returned by readFile
+func (s *Memory) readOCILayout(readFile func(name string) ([]byte, error)) error {
+	layoutJSON, err := readFile(ocispec.ImageLayoutFile)
+	if err != nil {
+		return err
+	}
+	var layout ocispec.ImageLayout
+	if err := json.Unmarshal(layoutJSON, &layout); err != nil {
+		return err
+	}
+	if layout.Version != ocispec.ImageLayoutVersion {
+		return ErrUnsupportedVersion
+	}
+
+	indexJSON, err := readFile(OCIImageIndexFile)
+	if err != nil {
+		return err
+	}
+	var index ocispec.Index
+	if err := json.Unmarshal(indexJSON, &index); err != nil {
+		return err
+	}
+
+	seen := make(map[digest.Digest]bool)
+	var load func(desc ocispec.Descriptor) error
+	load = func(desc ocispec.Descriptor) error {
+		if seen[desc.Digest] {
+			return nil
+		}
+		seen[desc.Digest] = true
+		if err := desc.Digest.Validate(); err != nil {
+			return err
+		}
+		content, err := readFile(blobPath(desc.Digest))
+		if err != nil {
+			return err
+		}
+		if int64(len(content)) != desc.Size || desc.Digest.Algorithm().FromBytes(content) != desc.Digest {
+			return fmt.Errorf("content of %s does not match its descriptor", desc.Digest)
+		}
+		s.Set(desc, content)
+		children, err := manifestChildren(desc.MediaType, content)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if err := load(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, desc := range index.Manifests {
+		ref := desc.Annotations[ocispec.AnnotationRefName]
+		desc.Annotations = withoutAnnotation(desc.Annotations, ocispec.AnnotationRefName)
+		// the ref is set first, to pin the root while its children are loaded
+		// into a store with a memory limit
+		if ref != "" {
+			s.setRef(ref, desc)
+		}
+		if err := load(desc); err != nil {
+			if ref != "" {
+				s.lock.Lock()
+				delete(s.refMap, ref)
+				s.lock.Unlock()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// blobPath returns the path of a blob within an OCI image layout
+func blobPath(dgst digest.Digest) string {
+	return strings.Join([]string{"blobs", dgst.Algorithm().String(), dgst.Encoded()}, "/")
+}
+
+// withoutAnnotation returns a copy of the annotations without key, or nil if
+// no other annotation is left
+func withoutAnnotation(annotations map[string]string, key string) map[string]string {
+	var result map[string]string
+	for k, v := range annotations {
+		if k == key {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string)
+		}
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,241 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/content"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	var evicted []ocispec.Descriptor
+	store := content.NewMemory(
+		content.WithMemoryLimit(10),
+		content.WithEvictionCallback(func(desc ocispec.Descriptor) {
+			evicted = append(evicted, desc)
+		}),
+	)
+
+	manifest := []byte("manifest")
+	manifestDesc, err := store.Add("", ocispec.MediaTypeImageManifest, manifest)
+	if err != nil {
+		t.Fatalf("unable to add manifest: %v", err)
+	}
+	if err := store.StoreManifest("ref", manifestDesc, manifest); err != nil {
+		t.Fatalf("unable to store manifest: %v", err)
+	}
+	a, _ := store.Add("a", "", []byte("a"))
+	b, _ := store.Add("b", "", []byte("b"))
+
+	// use a, so that b is the least recently used
+	if _, _, ok := store.Get(a); !ok {
+		t.Fatalf("a not found")
+	}
+	c, _ := store.Add("c", "", []byte("c"))
+
+	if len(evicted) != 1 || evicted[0].Digest != b.Digest {
+		t.Fatalf("expected only b to be evicted, got %v", evicted)
+	}
+	if _, _, ok := store.GetByName("b"); ok {
+		t.Errorf("b still found by name after eviction")
+	}
+	for _, desc := range []ocispec.Descriptor{manifestDesc, a, c} {
+		if _, _, ok := store.Get(desc); !ok {
+			t.Errorf("%v evicted unexpectedly", desc.Digest)
+		}
+	}
+
+	// the manifest is pinned by its ref, and the content being added is kept,
+	// even when they exceed the limit together
+	big, err := store.Add("big", "", []byte("0123456789"))
+	if err != nil {
+		t.Fatalf("unable to add big: %v", err)
+	}
+	if _, _, ok := store.Get(manifestDesc); !ok {
+		t.Errorf("pinned manifest evicted")
+	}
+	if _, _, ok := store.Get(big); !ok {
+		t.Errorf("big evicted while being added")
+	}
+	if len(evicted) != 3 {
+		t.Errorf("expected a and c to be evicted for big, got %v", evicted)
+	}
+
+	stats := store.Stats()
+	if stats.Evictions != int64(len(evicted)) {
+		t.Errorf("mismatched evictions, actual %d, expected %d", stats.Evictions, len(evicted))
+	}
+	if stats.Hits != 6 || stats.Misses != 1 {
+		t.Errorf("unexpected hits %d and misses %d", stats.Hits, stats.Misses)
+	}
+	if stats.Size != int64(len(manifest))+big.Size {
+		t.Errorf("unexpected size %d", stats.Size)
+	}
+}
+
+func TestMemoryExportImport(t *testing.T) {
+	ref := "localhost:5000/export:test"
+	store := content.NewMemory()
+	desc, _ := store.Add("hello.txt", "", []byte("Hello World!"))
+	manifest, manifestDesc, config, configDesc, err := content.GenerateManifestAndConfig(nil, nil, desc)
+	if err != nil {
+		t.Fatalf("unable to generate manifest: %v", err)
+	}
+	store.Set(configDesc, config)
+	if err := store.StoreManifest(ref, manifestDesc, manifest); err != nil {
+		t.Fatalf("unable to store manifest: %v", err)
+	}
+
+	verify := func(imported *content.Memory) {
+		_, actual, err := imported.Resolve(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("unable to resolve imported ref: %v", err)
+		}
+		if actual.Digest != manifestDesc.Digest {
+			t.Errorf("mismatched imported ref, actual %v, expected %v", actual.Digest, manifestDesc.Digest)
+		}
+		_, b, ok := imported.GetByName("hello.txt")
+		if !ok || string(b) != "Hello World!" {
+			t.Errorf("imported file missing or mismatched: %q", b)
+		}
+		if _, _, ok := imported.Get(configDesc); !ok {
+			t.Errorf("imported config missing")
+		}
+	}
+
+	// directory
+	rootPath, err := ioutil.TempDir("", "oras_memory_export")
+	if err != nil {
+		t.Fatalf("unable to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(rootPath)
+	if err := store.ExportOCI(rootPath); err != nil {
+		t.Fatalf("unable to export to directory: %v", err)
+	}
+	oci, err := content.NewOCI(rootPath)
+	if err != nil {
+		t.Fatalf("unable to open exported layout: %v", err)
+	}
+	if _, ok := oci.ListReferences()[ref]; !ok {
+		t.Errorf("ref missing from exported index")
+	}
+	imported := content.NewMemory()
+	if err := imported.ImportOCI(rootPath); err != nil {
+		t.Fatalf("unable to import from directory: %v", err)
+	}
+	verify(imported)
+
+	// the imported ref is pinned before its children are loaded
+	imported = content.NewMemory(content.WithMemoryLimit(1))
+	if err := imported.ImportOCI(rootPath); err != nil {
+		t.Fatalf("unable to import under a memory limit: %v", err)
+	}
+	if _, actual, err := imported.Resolve(context.Background(), ref); err != nil || actual.Digest != manifestDesc.Digest {
+		t.Fatalf("imported ref missing under a memory limit: %v", err)
+	}
+	if _, _, ok := imported.Get(manifestDesc); !ok {
+		t.Errorf("imported manifest evicted under a memory limit")
+	}
+
+	// archive
+	var buf bytes.Buffer
+	if err := store.ExportOCIArchive(&buf); err != nil {
+		t.Fatalf("unable to export to archive: %v", err)
+	}
+	imported = content.NewMemory()
+	if err := imported.ImportOCIArchive(&buf); err != nil {
+		t.Fatalf("unable to import from archive: %v", err)
+	}
+	verify(imported)
+}
+
+func TestMemoryDelete(t *testing.T) {
+	ctx := context.Background()
+	store := content.NewMemory()
+
+	blobDesc, err := store.Add("blob", "", []byte("blob"))
+	if err != nil {
+		t.Fatalf("unable to add blob: %v", err)
+	}
+	manifest := []byte("manifest")
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifest),
+		Size:      int64(len(manifest)),
+	}
+	for _, ref := range []string{"localhost:5000/test:v1", "localhost:5000/test:v2", "localhost:5000/other:v1"} {
+		if err := store.StoreManifest(ref, manifestDesc, manifest); err != nil {
+			t.Fatalf("unable to store manifest: %v", err)
+		}
+	}
+
+	if err := store.Untag(ctx, "localhost:5000/test:v2"); err != nil {
+		t.Fatalf("unable to untag: %v", err)
+	}
+	if _, _, err := store.Resolve(ctx, "localhost:5000/test:v2"); err == nil {
+		t.Fatalf("expected untagged reference to be gone")
+	}
+	if err := store.Untag(ctx, "localhost:5000/test:v2"); !errors.Is(err, content.ErrNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	if err := store.DeleteManifest(ctx, "localhost:5000/test", manifestDesc.Digest); err != nil {
+		t.Fatalf("unable to delete manifest: %v", err)
+	}
+	if _, _, err := store.Resolve(ctx, "localhost:5000/test:v1"); err == nil {
+		t.Fatalf("expected the tag of the deleted manifest to be gone")
+	}
+	if _, _, err := store.Resolve(ctx, "localhost:5000/other:v1"); err != nil {
+		t.Fatalf("expected the tag of another repository to remain: %v", err)
+	}
+	if _, _, ok := store.Get(manifestDesc); !ok {
+		t.Fatalf("expected the manifest referenced by another repository to remain")
+	}
+	if err := store.DeleteManifest(ctx, "localhost:5000/other", manifestDesc.Digest); err != nil {
+		t.Fatalf("unable to delete manifest: %v", err)
+	}
+	if _, _, err := store.Resolve(ctx, "localhost:5000/other:v1"); err == nil {
+		t.Fatalf("expected the tag of the deleted manifest to be gone")
+	}
+	if _, _, ok := store.Get(manifestDesc); ok {
+		t.Fatalf("expected the manifest to be deleted")
+	}
+	if err := store.DeleteManifest(ctx, "localhost:5000/other", manifestDesc.Digest); !errors.Is(err, content.ErrNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	if err := store.DeleteBlob(ctx, "localhost:5000/test", blobDesc.Digest); err != nil {
+		t.Fatalf("unable to delete blob: %v", err)
+	}
+	if _, _, ok := store.GetByName("blob"); ok {
+		t.Fatalf("expected the blob to be deleted")
+	}
+	if err := store.DeleteBlob(ctx, "localhost:5000/test", blobDesc.Digest); !errors.Is(err, content.ErrNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if size := store.Stats().Size; size != 0 {
+		t.Fatalf("expected an empty store, got size %d", size)
+	}
+}
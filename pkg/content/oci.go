@@ -17,20 +17,24 @@ package content
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/containerd/containerd/content"
 	"github.com/containerd/containerd/content/local"
+	"github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/remotes"
 	"github.com/opencontainers/go-digest"
 	specs "github.com/opencontainers/image-spec/specs-go"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+
+	"oras.land/oras-go/pkg/artifact"
 )
 
 // OCI provides content from the file system with the OCI-Image layout.
@@ -41,6 +45,11 @@ type OCI struct {
 	root    string
 	index   *ocispec.Index
 	nameMap map[string]ocispec.Descriptor
+
+	// the referrers of each subject found in the blobs scanned so far
+	referrerLock sync.Mutex
+	scanned      map[digest.Digest]bool
+	referrers    map[digest.Digest][]artifact.Descriptor
 }
 
 // NewOCI creates a new OCI store
@@ -164,7 +173,18 @@ func (s *OCI) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
 	if len(parts) > 1 {
 		hash = parts[1]
 	}
-	return &ociPusher{oci: s, ref: baseRef, digest: hash}, nil
+	return &ociPusher{oci: s, ref: baseRef, digest: hash, tag: baseRef != ""}, nil
+}
+
+// UntaggedPusher returns a pusher which does not tag the root with ref, such
+// as for the referrers
+func (s *OCI) UntaggedPusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	pusher, err := s.Pusher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	pusher.(*ociPusher).tag = false
+	return pusher, nil
 }
 
 // AddReference adds or updates an reference to index.
@@ -218,6 +238,73 @@ func (s *OCI) ListReferences() map[string]ocispec.Descriptor {
 	return s.nameMap
 }
 
+// Tags lists the tags of the repository referenced by ref. References without
+// a repository, such as plain tags, are listed with an empty ref.
+func (s *OCI) Tags(ctx context.Context, ref string) ([]string, error) {
+	return listTags(s.refNames(), ref), nil
+}
+
+// Repositories lists the repositories of the host
+func (s *OCI) Repositories(ctx context.Context, host string) ([]string, error) {
+	return listRepositories(s.refNames(), host), nil
+}
+
+func (s *OCI) refNames() []string {
+	names := make([]string, 0, len(s.nameMap))
+	for name := range s.nameMap {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Discover returns the manifests of the layout referring to the subject, by
+// scanning the blobs. The blobs of an OCI layout are shared by all the
+// references, so ref is ignored. Each blob is read once: the referrers found
+// are kept for the next calls, which only read the blobs added since.
+func (s *OCI) Discover(ctx context.Context, ref string, subject ocispec.Descriptor, artifactType string) ([]artifact.Descriptor, error) {
+	s.referrerLock.Lock()
+	defer s.referrerLock.Unlock()
+	if s.scanned == nil {
+		s.scanned = make(map[digest.Digest]bool)
+		s.referrers = make(map[digest.Digest][]artifact.Descriptor)
+	}
+
+	present := make(map[digest.Digest]bool)
+	err := s.Store.Walk(ctx, func(info content.Info) error {
+		present[info.Digest] = true
+		if s.scanned[info.Digest] {
+			return nil
+		}
+		if info.Size <= maxReferrerSize {
+			desc := ocispec.Descriptor{
+				Digest: info.Digest,
+				Size:   info.Size,
+			}
+			manifest, err := content.ReadBlob(ctx, s.Store, desc)
+			if err != nil {
+				return err
+			}
+			if referrer, referred, ok := parseReferrer(desc, manifest); ok {
+				s.referrers[referred] = append(s.referrers[referred], referrer)
+			}
+		}
+		s.scanned[info.Digest] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the referrers deleted since they were scanned are left out
+	var referrers []artifact.Descriptor
+	for _, referrer := range s.referrers[subject.Digest] {
+		if present[referrer.Digest] {
+			referrers = append(referrers, referrer)
+		}
+	}
+	return sortReferrers(artifact.FilterReferrers(referrers, artifactType)), nil
+}
+
 // validateOCILayoutFile ensures the `oci-layout` file
 func (s *OCI) validateOCILayoutFile() error {
 	layoutFilePath := filepath.Join(s.root, ocispec.ImageLayoutFile)
@@ -276,10 +363,69 @@ func (s *OCI) Walk(ctx context.Context, fn content.WalkFunc, filters ...string)
 	return errors.New("not yet implemented: Walk (content.Store interface)")
 }
 
-// TODO: implement (needed to create a content.Store)
 // Delete removes the content from the store.
 func (s *OCI) Delete(ctx context.Context, dgst digest.Digest) error {
-	return errors.New("not yet implemented: Delete (content.Store interface)")
+	// the file store does not report missing content on delete
+	if _, err := s.Store.Info(ctx, dgst); err != nil {
+		if errdefs.IsNotFound(err) {
+			return errors.Wrapf(ErrNotFound, "content %s", dgst)
+		}
+		return err
+	}
+	return s.Store.Delete(ctx, dgst)
+}
+
+// DeleteManifest deletes the references to the manifest in the repository of
+// ref, saving the index, and the manifest itself unless references of other
+// repositories still point to it.
+func (s *OCI) DeleteManifest(ctx context.Context, ref string, dgst digest.Digest) error {
+	if _, err := s.Store.Info(ctx, dgst); err != nil {
+		if errdefs.IsNotFound(err) {
+			return errors.Wrapf(ErrNotFound, "manifest %s", dgst)
+		}
+		return err
+	}
+	repository, _ := splitReference(ref)
+	var names []string
+	referenced := false
+	for name, desc := range s.nameMap {
+		if desc.Digest != dgst {
+			continue
+		}
+		if repo, _ := splitReference(name); repo == repository {
+			names = append(names, name)
+		} else {
+			referenced = true
+		}
+	}
+	if len(names) > 0 {
+		for _, name := range names {
+			s.DeleteReference(name)
+		}
+		if err := s.SaveIndex(); err != nil {
+			return err
+		}
+	}
+	if referenced {
+		return nil
+	}
+	return s.Store.Delete(ctx, dgst)
+}
+
+// DeleteBlob deletes the blob. The blobs of an OCI layout are shared by all
+// the references, so ref is ignored.
+func (s *OCI) DeleteBlob(ctx context.Context, ref string, dgst digest.Digest) error {
+	return s.Delete(ctx, dgst)
+}
+
+// Untag removes the reference from the index, keeping the manifest it
+// references, and saves the index.
+func (s *OCI) Untag(ctx context.Context, ref string) error {
+	if _, ok := s.nameMap[ref]; !ok {
+		return errors.Wrapf(ErrNotFound, "reference %s", ref)
+	}
+	s.DeleteReference(ref)
+	return s.SaveIndex()
 }
 
 // TODO: implement (needed to create a content.Store)
@@ -314,15 +460,15 @@ type ociPusher struct {
 	oci    *OCI
 	ref    string
 	digest string
+	tag    bool
 }
 
 // Push get a writer for a single Descriptor
 func (p *ociPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
 	// do we need to create a tag?
-	switch desc.MediaType {
-	case ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex:
+	if IsManifestMediaType(desc.MediaType) {
 		// if the hash of the content matches that which was provided as the hash for the root, mark it
-		if p.digest != "" && p.digest == desc.Digest.String() {
+		if p.tag && p.digest != "" && p.digest == desc.Digest.String() {
 			if err := p.oci.LoadIndex(); err != nil {
 				return nil, err
 			}
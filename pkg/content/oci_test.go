@@ -0,0 +1,93 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	ctrcontent "github.com/containerd/containerd/content"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/content"
+)
+
+func TestOCIDelete(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "oras_oci_test")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := content.NewOCI(dir)
+	if err != nil {
+		t.Fatalf("unable to create OCI store: %v", err)
+	}
+	manifest := []byte("manifest")
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifest),
+		Size:      int64(len(manifest)),
+	}
+	if err := ctrcontent.WriteBlob(ctx, store, "manifest", bytes.NewReader(manifest), desc); err != nil {
+		t.Fatalf("unable to write manifest: %v", err)
+	}
+	store.AddReference("v1", desc)
+	store.AddReference("v2", desc)
+	store.AddReference("localhost:5000/other:v1", desc)
+	if err := store.SaveIndex(); err != nil {
+		t.Fatalf("unable to save index: %v", err)
+	}
+
+	if err := store.Untag(ctx, "v2"); err != nil {
+		t.Fatalf("unable to untag: %v", err)
+	}
+	if err := store.Untag(ctx, "v2"); !errors.Is(err, content.ErrNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	if err := store.DeleteManifest(ctx, "", desc.Digest); err != nil {
+		t.Fatalf("unable to delete manifest: %v", err)
+	}
+	if _, err := store.ReaderAt(ctx, desc); err != nil {
+		t.Fatalf("expected the manifest referenced by another repository to remain: %v", err)
+	}
+	if err := store.DeleteManifest(ctx, "localhost:5000/other", desc.Digest); err != nil {
+		t.Fatalf("unable to delete manifest: %v", err)
+	}
+	if _, err := store.ReaderAt(ctx, desc); err == nil {
+		t.Fatalf("expected the manifest to be deleted")
+	}
+
+	// the index on disk no longer has the references
+	reloaded, err := content.NewOCI(dir)
+	if err != nil {
+		t.Fatalf("unable to reload OCI store: %v", err)
+	}
+	if refs := reloaded.ListReferences(); len(refs) != 0 {
+		t.Fatalf("expected no references, got %v", refs)
+	}
+
+	if err := store.DeleteBlob(ctx, "", desc.Digest); !errors.Is(err, content.ErrNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package content
 
 import (
+	"crypto/rsa"
 	"errors"
 
 	"github.com/opencontainers/go-digest"
@@ -27,6 +28,7 @@ type WriterOpts struct {
 	Blocksize           int
 	MultiWriterIngester bool
 	IgnoreNoName        bool
+	DecryptionKeys      []*rsa.PrivateKey
 }
 
 type WriterOpt func(*WriterOpts) error
@@ -110,3 +112,12 @@ func WithIgnoreNoName() WriterOpt {
 		return nil
 	}
 }
+
+// WithDecryptionKeys provide the private keys to decrypt the encrypted layers,
+// whose media type has the EncryptedSuffix. See NewDecryptWriter.
+func WithDecryptionKeys(keys ...*rsa.PrivateKey) WriterOpt {
+	return func(w *WriterOpts) error {
+		w.DecryptionKeys = append(w.DecryptionKeys, keys...)
+		return nil
+	}
+}
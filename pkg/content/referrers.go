@@ -0,0 +1,87 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/artifact"
+)
+
+// maxReferrerSize is the maximum size of a manifest scanned for its subject
+const maxReferrerSize = 4 * 1024 * 1024
+
+// referrerManifest holds the fields of a manifest which refers to a subject
+type referrerManifest struct {
+	MediaType    string              `json:"mediaType"`
+	ArtifactType string              `json:"artifactType"`
+	Config       *ocispec.Descriptor `json:"config"`
+	Subject      *ocispec.Descriptor `json:"subject"`
+	Annotations  map[string]string   `json:"annotations"`
+}
+
+// referrerOf returns the descriptor of the manifest if it refers to the subject
+func referrerOf(desc ocispec.Descriptor, content []byte, subject digest.Digest) (artifact.Descriptor, bool) {
+	referrer, referred, ok := parseReferrer(desc, content)
+	if !ok || referred != subject {
+		return artifact.Descriptor{}, false
+	}
+	return referrer, true
+}
+
+// parseReferrer returns the descriptor of the manifest, along with the digest
+// of its subject, if it refers to a subject
+func parseReferrer(desc ocispec.Descriptor, content []byte) (artifact.Descriptor, digest.Digest, bool) {
+	var manifest referrerManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return artifact.Descriptor{}, "", false
+	}
+	if manifest.Subject == nil {
+		return artifact.Descriptor{}, "", false
+	}
+	mediaType := manifest.MediaType
+	if mediaType == "" {
+		mediaType = desc.MediaType
+	}
+	if mediaType != ocispec.MediaTypeImageManifest && mediaType != artifact.MediaTypeArtifactManifest {
+		return artifact.Descriptor{}, "", false
+	}
+	artifactType := manifest.ArtifactType
+	if artifactType == "" && manifest.Config != nil {
+		artifactType = manifest.Config.MediaType
+	}
+	return artifact.Descriptor{
+		Descriptor: ocispec.Descriptor{
+			MediaType:   mediaType,
+			Digest:      desc.Digest,
+			Size:        desc.Size,
+			Annotations: manifest.Annotations,
+		},
+		ArtifactType: artifactType,
+	}, manifest.Subject.Digest, true
+}
+
+// sortReferrers sorts the referrers by digest, for stores without an order
+func sortReferrers(referrers []artifact.Descriptor) []artifact.Descriptor {
+	sort.Slice(referrers, func(i, j int) bool {
+		return referrers[i].Digest < referrers[j].Digest
+	})
+	return referrers
+}
@@ -0,0 +1,145 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	ctrcontent "github.com/containerd/containerd/content"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/target"
+)
+
+// referrerManifest is an image manifest with the subject and artifact type
+// fields of the referrers API
+type referrerManifest struct {
+	specs.Versioned
+	MediaType    string               `json:"mediaType"`
+	ArtifactType string               `json:"artifactType,omitempty"`
+	Config       ocispec.Descriptor   `json:"config"`
+	Layers       []ocispec.Descriptor `json:"layers"`
+	Subject      *ocispec.Descriptor  `json:"subject,omitempty"`
+}
+
+func TestDiscover(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "oras_referrers_test")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ociStore, err := content.NewOCI(dir)
+	if err != nil {
+		t.Fatalf("unable to create OCI store: %v", err)
+	}
+	memoryStore := content.NewMemory()
+	add := func(mediaType string, blob []byte) ocispec.Descriptor {
+		desc := ocispec.Descriptor{
+			MediaType: mediaType,
+			Digest:    digest.FromBytes(blob),
+			Size:      int64(len(blob)),
+		}
+		memoryStore.Set(desc, blob)
+		if err := ctrcontent.WriteBlob(ctx, ociStore, desc.Digest.String(), bytes.NewReader(blob), desc); err != nil {
+			t.Fatalf("unable to write blob: %v", err)
+		}
+		return desc
+	}
+	addManifest := func(manifest referrerManifest) ocispec.Descriptor {
+		manifest.SchemaVersion = 2
+		manifest.MediaType = ocispec.MediaTypeImageManifest
+		blob, err := json.Marshal(manifest)
+		if err != nil {
+			t.Fatalf("unable to marshal manifest: %v", err)
+		}
+		return add(ocispec.MediaTypeImageManifest, blob)
+	}
+
+	config := add("application/vnd.example.config", []byte("{}"))
+	subject := addManifest(referrerManifest{Config: config})
+	signature := addManifest(referrerManifest{
+		ArtifactType: "application/vnd.example.signature",
+		Config:       config,
+		Subject:      &subject,
+	})
+	sbom := addManifest(referrerManifest{
+		Config:  ocispec.Descriptor{MediaType: "application/vnd.example.sbom", Digest: config.Digest, Size: config.Size},
+		Subject: &subject,
+	})
+	// a referrer of another subject
+	addManifest(referrerManifest{
+		ArtifactType: "application/vnd.example.signature",
+		Config:       config,
+		Subject:      &signature,
+	})
+
+	for _, discoverer := range []target.Discoverer{memoryStore, ociStore} {
+		referrers, err := discoverer.Discover(ctx, "", subject, "")
+		if err != nil {
+			t.Fatalf("unable to discover: %v", err)
+		}
+		found := make(map[digest.Digest]string)
+		for _, referrer := range referrers {
+			found[referrer.Digest] = referrer.ArtifactType
+		}
+		if len(found) != 2 || found[signature.Digest] != "application/vnd.example.signature" || found[sbom.Digest] != "application/vnd.example.sbom" {
+			t.Fatalf("unexpected referrers %v", referrers)
+		}
+
+		referrers, err = discoverer.Discover(ctx, "", subject, "application/vnd.example.sbom")
+		if err != nil {
+			t.Fatalf("unable to discover: %v", err)
+		}
+		if len(referrers) != 1 || referrers[0].Digest != sbom.Digest {
+			t.Fatalf("unexpected filtered referrers %v", referrers)
+		}
+	}
+
+	// the OCI store finds the referrers added and deleted since it last
+	// scanned its blobs
+	attestation := addManifest(referrerManifest{
+		ArtifactType: "application/vnd.example.attestation",
+		Config:       config,
+		Subject:      &subject,
+	})
+	referrers, err := ociStore.Discover(ctx, "", subject, "application/vnd.example.attestation")
+	if err != nil {
+		t.Fatalf("unable to discover: %v", err)
+	}
+	if len(referrers) != 1 || referrers[0].Digest != attestation.Digest {
+		t.Fatalf("unexpected added referrers %v", referrers)
+	}
+	if err := ociStore.DeleteBlob(ctx, "", attestation.Digest); err != nil {
+		t.Fatalf("unable to delete referrer: %v", err)
+	}
+	referrers, err = ociStore.Discover(ctx, "", subject, "")
+	if err != nil {
+		t.Fatalf("unable to discover: %v", err)
+	}
+	if len(referrers) != 2 {
+		t.Fatalf("unexpected referrers after deletion %v", referrers)
+	}
+}
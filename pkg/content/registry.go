@@ -16,7 +16,6 @@ package content
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"net/http"
 	"os"
@@ -25,6 +24,7 @@ import (
 
 	"github.com/containerd/containerd/remotes"
 	"github.com/containerd/containerd/remotes/docker"
+	"github.com/containerd/containerd/remotes/docker/config"
 )
 
 // RegistryOptions provide configuration options to a Registry
@@ -34,51 +34,114 @@ type RegistryOptions struct {
 	Password  string
 	Insecure  bool
 	PlainHTTP bool
+	// Transport configures the HTTP transport of the Registry. Each Registry
+	// gets its own http.Client, so the options never affect other clients.
+	Transport TransportOptions
+	// HostsDir is the root of a containerd hosts configuration directory.
+	// When set, the hosts of a registry are read from
+	// <HostsDir>/<host>/hosts.toml, which may declare mirrors with their
+	// capabilities and TLS settings. Mirrors are tried in order before falling
+	// back to the upstream registry.
+	HostsDir string
 }
 
 // Registry provides content from a spec-compliant registry. Create an use a new one for each
 // registry with unique configuration of RegistryOptions.
 type Registry struct {
 	remotes.Resolver
+	hosts docker.RegistryHosts
 }
 
 // NewRegistry creates a new Registry store
 func NewRegistry(opts RegistryOptions) (*Registry, error) {
+	hosts, err := newRegistryHosts(opts)
+	if err != nil {
+		return nil, err
+	}
 	return &Registry{
-		Resolver: newResolver(opts.Username, opts.Password, opts.Insecure, opts.PlainHTTP, opts.Configs...),
+		Resolver: docker.NewResolver(docker.ResolverOptions{
+			Hosts: hosts,
+		}),
+		hosts: hosts,
 	}, nil
 }
 
-func newResolver(username, password string, insecure bool, plainHTTP bool, configs ...string) remotes.Resolver {
+// newRegistryHosts creates the function returning the endpoints of a registry
+// host, either from the hosts configuration directory or from the defaults
+func newRegistryHosts(opts RegistryOptions) (docker.RegistryHosts, error) {
+	credentials := newCredentials(opts.Username, opts.Password, opts.Configs...)
 
-	opts := docker.ResolverOptions{
-		PlainHTTP: plainHTTP,
+	if opts.HostsDir == "" {
+		client, err := newHTTPClient(opts)
+		if err != nil {
+			return nil, err
+		}
+		plainHTTP := docker.MatchLocalhost
+		if opts.PlainHTTP {
+			plainHTTP = docker.MatchAllHosts
+		}
+		return docker.ConfigureDefaultRegistries(
+			docker.WithClient(client),
+			docker.WithAuthorizer(docker.NewDockerAuthorizer(
+				docker.WithAuthClient(client),
+				docker.WithAuthCreds(credentials),
+			)),
+			docker.WithPlainHTTP(plainHTTP),
+		), nil
 	}
 
-	client := http.DefaultClient
-	if insecure {
-		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
+	// fail early on invalid transport options, as the hosts are only
+	// configured when a request is made
+	if _, err := newTLSConfig(opts.Transport, opts.Insecure); err != nil {
+		return nil, err
+	}
+	configureHosts := func(scheme, host string) ([]docker.RegistryHost, error) {
+		// the TLS configuration is the default one of the hosts, which add
+		// their own CA files, client certificates and verification setting to
+		// a copy sharing its root pool: it is created for every call so that
+		// no host trusts the CA files of another
+		tlsConfig, err := newTLSConfig(opts.Transport, opts.Insecure)
+		if err != nil {
+			return nil, err
 		}
+		return config.ConfigureHosts(context.Background(), config.HostOptions{
+			HostDir:       config.HostDirFromRoot(opts.HostsDir),
+			Credentials:   credentials,
+			DefaultScheme: scheme,
+			DefaultTLS:    tlsConfig,
+			UpdateClient: func(client *http.Client) error {
+				return configureTransport(client.Transport.(*http.Transport), opts.Transport)
+			},
+		})(host)
 	}
-	opts.Client = client
+	return func(host string) ([]docker.RegistryHost, error) {
+		if opts.PlainHTTP {
+			return configureHosts("http", host)
+		}
+		if local, _ := docker.MatchLocalhost(host); local {
+			return configureHosts("http", host)
+		}
+		return configureHosts("https", host)
+	}, nil
+}
 
+// newCredentials returns the credentials of the registry, either the static
+// username and password or the ones of the docker config files
+func newCredentials(username, password string, configs ...string) func(string) (string, string, error) {
 	if username != "" || password != "" {
-		opts.Credentials = func(hostName string) (string, string, error) {
+		return func(hostName string) (string, string, error) {
 			return username, password, nil
 		}
-		return docker.NewResolver(opts)
 	}
 	cli, err := auth.NewClient(configs...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "WARNING: Error loading auth file: %v\n", err)
+		return nil
 	}
-	resolver, err := cli.Resolver(context.Background(), client, plainHTTP)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "WARNING: Error loading resolver: %v\n", err)
-		resolver = docker.NewResolver(opts)
+	if provider, ok := cli.(interface {
+		Credential(string) (string, string, error)
+	}); ok {
+		return provider.Credential
 	}
-	return resolver
+	return nil
 }
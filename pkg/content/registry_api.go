@@ -0,0 +1,454 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+
+	"oras.land/oras-go/pkg/artifact"
+)
+
+// endpoints returns the endpoints of the registry host with the capability
+func (r *Registry) endpoints(host string, capability docker.HostCapabilities) ([]docker.RegistryHost, error) {
+	hosts, err := r.hosts(host)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []docker.RegistryHost
+	for _, h := range hosts {
+		if h.Capabilities.Has(capability) {
+			endpoints = append(endpoints, h)
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, errors.Wrapf(ErrNotFound, "no endpoint of %s can be used", host)
+	}
+	return endpoints, nil
+}
+
+// endpointURL returns the URL of the API path on the endpoint
+func endpointURL(endpoint docker.RegistryHost, path string) string {
+	return fmt.Sprintf("%s://%s%s%s", endpoint.Scheme, endpoint.Host, endpoint.Path, path)
+}
+
+// send sends a request to the endpoint. If the registry asks for credentials,
+// the request is authorized for the scope of the context and sent again.
+func send(ctx context.Context, endpoint docker.RegistryHost, method, u string, header http.Header) (*http.Response, error) {
+	client := endpoint.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	do := func() (*http.Response, error) {
+		req, err := http.NewRequest(method, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		for key, values := range endpoint.Header {
+			req.Header[key] = append(req.Header[key], values...)
+		}
+		for key, values := range header {
+			req.Header[key] = append(req.Header[key], values...)
+		}
+		if endpoint.Authorizer != nil {
+			if err := endpoint.Authorizer.Authorize(ctx, req); err != nil {
+				return nil, err
+			}
+		}
+		return client.Do(req)
+	}
+	resp, err := do()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized || endpoint.Authorizer == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+	if err := endpoint.Authorizer.AddResponses(ctx, []*http.Response{resp}); err != nil {
+		return nil, err
+	}
+	return do()
+}
+
+// responseError converts an unexpected response of the registry to an error
+func responseError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	err := errors.Errorf("unexpected status from %s request to %s: %s", resp.Request.Method, resp.Request.URL, resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		err = errors.Wrap(ErrNotFound, err.Error())
+	case resp.StatusCode == http.StatusMethodNotAllowed || hasErrorCode(body, "UNSUPPORTED"):
+		err = errors.Wrap(ErrUnsupported, err.Error())
+	}
+	if len(body) > 0 {
+		err = errors.Wrap(err, strings.TrimSpace(string(body)))
+	}
+	return err
+}
+
+// hasErrorCode returns true if the error response body of the registry
+// contains the error code
+func hasErrorCode(body []byte, code string) bool {
+	var response struct {
+		Errors []struct {
+			Code string `json:"code"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false
+	}
+	for _, e := range response.Errors {
+		if e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// nextLink returns the URL of the next page from the Link header, if any
+func nextLink(resp *http.Response) (string, error) {
+	link := resp.Header.Get("Link")
+	if link == "" {
+		return "", nil
+	}
+	start := strings.Index(link, "<")
+	end := strings.Index(link, ">")
+	if start < 0 || end < start || !strings.Contains(link[end:], `rel="next"`) {
+		return "", nil
+	}
+	next, err := resp.Request.URL.Parse(link[start+1 : end])
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid Link header %q", link)
+	}
+	return next.String(), nil
+}
+
+// list fetches all the pages of a listing API of the registry host. Each page
+// is passed to decode. The endpoints of the host are tried in order until one
+// responds to the first page.
+func (r *Registry) list(ctx context.Context, host, path string, header http.Header, decode func(io.Reader) error) error {
+	endpoints, err := r.endpoints(host, docker.HostCapabilityResolve)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, endpoint := range endpoints {
+		resp, err := send(ctx, endpoint, http.MethodGet, endpointURL(endpoint, path), header)
+		if err == nil && resp.StatusCode != http.StatusOK {
+			err = responseError(resp)
+			resp.Body.Close()
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for {
+			err := decode(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return err
+			}
+			next, err := nextLink(resp)
+			if err != nil || next == "" {
+				return err
+			}
+			if resp, err = send(ctx, endpoint, http.MethodGet, next, header); err != nil {
+				return err
+			}
+			if resp.StatusCode != http.StatusOK {
+				err := responseError(resp)
+				resp.Body.Close()
+				return err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Tags lists the tags of the repository referenced by ref
+func (r *Registry) Tags(ctx context.Context, ref string) ([]string, error) {
+	spec, host, repository, err := parseRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	if ctx, err = docker.ContextWithRepositoryScope(ctx, spec, false); err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	err = r.list(ctx, host, "/"+repository+"/tags/list", nil, func(body io.Reader) error {
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return errors.Wrap(err, "invalid tag list")
+		}
+		tags = append(tags, page.Tags...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Repositories lists the repositories of the registry host
+func (r *Registry) Repositories(ctx context.Context, host string) ([]string, error) {
+	ctx = docker.WithScope(ctx, "registry:catalog:*")
+
+	var repositories []string
+	err := r.list(ctx, host, "/_catalog", nil, func(body io.Reader) error {
+		var page struct {
+			Repositories []string `json:"repositories"`
+		}
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return errors.Wrap(err, "invalid catalog")
+		}
+		repositories = append(repositories, page.Repositories...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(repositories)
+	return repositories, nil
+}
+
+// parseRepository parses the reference into its host and repository
+func parseRepository(ref string) (reference.Spec, string, string, error) {
+	spec, err := reference.Parse(ref)
+	if err != nil {
+		return reference.Spec{}, "", "", err
+	}
+	host := spec.Hostname()
+	return spec, host, strings.TrimPrefix(spec.Locator, host+"/"), nil
+}
+
+// delete deletes the object of the kind, manifests or blobs, from the
+// repository referenced by ref
+func (r *Registry) delete(ctx context.Context, ref, kind, object string) error {
+	_, host, repository, err := parseRepository(ref)
+	if err != nil {
+		return err
+	}
+	ctx = docker.WithScope(ctx, fmt.Sprintf("repository:%s:delete", repository))
+	endpoints, err := r.endpoints(host, docker.HostCapabilityPush)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/%s/%s/%s", repository, kind, object)
+	var firstErr error
+	for _, endpoint := range endpoints {
+		resp, err := send(ctx, endpoint, http.MethodDelete, endpointURL(endpoint, path), nil)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+			resp.Body.Close()
+			return nil
+		}
+		err = responseError(resp)
+		resp.Body.Close()
+		return err
+	}
+	return firstErr
+}
+
+// DeleteManifest deletes the manifest from the repository referenced by ref.
+// The registry removes the tags referencing the manifest.
+func (r *Registry) DeleteManifest(ctx context.Context, ref string, dgst digest.Digest) error {
+	return r.delete(ctx, ref, "manifests", dgst.String())
+}
+
+// DeleteBlob deletes the blob from the repository referenced by ref
+func (r *Registry) DeleteBlob(ctx context.Context, ref string, dgst digest.Digest) error {
+	return r.delete(ctx, ref, "blobs", dgst.String())
+}
+
+// Untag deletes the tag of ref. Many registries do not support deleting tags,
+// in which case ErrUnsupported is returned.
+func (r *Registry) Untag(ctx context.Context, ref string) error {
+	spec, err := reference.Parse(ref)
+	if err != nil {
+		return err
+	}
+	tag, _ := reference.SplitObject(spec.Object)
+	tag = strings.TrimSuffix(tag, "@")
+	if tag == "" {
+		return errors.Wrapf(ErrNoName, "no tag in reference %s", ref)
+	}
+	return r.delete(ctx, ref, "manifests", tag)
+}
+
+// Discover returns the manifests referring to the subject in the repository
+// referenced by ref, using the referrers API. If the registry does not support
+// the API, the referrers are read from the index under the referrers tag.
+func (r *Registry) Discover(ctx context.Context, ref string, subject ocispec.Descriptor, artifactType string) ([]artifact.Descriptor, error) {
+	spec, host, repository, err := parseRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	if ctx, err = docker.ContextWithRepositoryScope(ctx, spec, false); err != nil {
+		return nil, err
+	}
+	referrers, err := r.referrersFromAPI(ctx, host, repository, subject.Digest, artifactType)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnsupported) {
+		referrers, err = r.referrersFromTag(ctx, spec.Locator, subject.Digest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// registries may ignore the filter
+	return artifact.FilterReferrers(referrers, artifactType), nil
+}
+
+// IndexReferrer adds the referrer to the index under the referrers tag of the
+// subject, if the registry does not support the referrers API. Registries
+// supporting the API index the referrers when they are pushed.
+func (r *Registry) IndexReferrer(ctx context.Context, ref string, subject ocispec.Descriptor, referrer artifact.Descriptor) error {
+	spec, host, repository, err := parseRepository(ref)
+	if err != nil {
+		return err
+	}
+	readCtx, err := docker.ContextWithRepositoryScope(ctx, spec, false)
+	if err != nil {
+		return err
+	}
+	_, err = r.referrersFromAPI(readCtx, host, repository, subject.Digest, "")
+	if err == nil || !(errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnsupported)) {
+		return err
+	}
+
+	referrers, err := r.referrersFromTag(readCtx, spec.Locator, subject.Digest)
+	if err != nil {
+		return err
+	}
+	for _, desc := range referrers {
+		if desc.Digest == referrer.Digest {
+			return nil
+		}
+	}
+	index, err := json.Marshal(artifact.Index{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2,
+		},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: append(referrers, referrer),
+	})
+	if err != nil {
+		return err
+	}
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(index),
+		Size:      int64(len(index)),
+	}
+
+	pusher, err := r.Pusher(ctx, spec.Locator+":"+artifact.ReferrersTag(subject.Digest))
+	if err != nil {
+		return err
+	}
+	writer, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	defer writer.Close()
+	if _, err := writer.Write(index); err != nil {
+		return err
+	}
+	if err := writer.Commit(ctx, desc.Size, desc.Digest); err != nil && !errdefs.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// referrersFromAPI lists the referrers of the subject with the referrers API
+func (r *Registry) referrersFromAPI(ctx context.Context, host, repository string, subject digest.Digest, artifactType string) ([]artifact.Descriptor, error) {
+	path := fmt.Sprintf("/%s/referrers/%s", repository, subject)
+	if artifactType != "" {
+		path += "?" + url.Values{"artifactType": {artifactType}}.Encode()
+	}
+	header := http.Header{
+		"Accept": {ocispec.MediaTypeImageIndex},
+	}
+	var referrers []artifact.Descriptor
+	err := r.list(ctx, host, path, header, func(body io.Reader) error {
+		var index artifact.Index
+		if err := json.NewDecoder(io.LimitReader(body, maxReferrerSize)).Decode(&index); err != nil {
+			return errors.Wrap(err, "invalid referrers index")
+		}
+		referrers = append(referrers, index.Manifests...)
+		return nil
+	})
+	return referrers, err
+}
+
+// referrersFromTag reads the referrers of the subject from the index under
+// the referrers tag in the repository. A missing tag means no referrers.
+func (r *Registry) referrersFromTag(ctx context.Context, locator string, subject digest.Digest) ([]artifact.Descriptor, error) {
+	ref := locator + ":" + artifact.ReferrersTag(subject)
+	_, desc, err := r.Resolve(ctx, ref)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if desc.Size > maxReferrerSize {
+		return nil, errors.Wrapf(ErrUnsupportedSize, "referrers index of %d bytes", desc.Size)
+	}
+	fetcher, err := r.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var index artifact.Index
+	if err := json.NewDecoder(io.LimitReader(rc, desc.Size)).Decode(&index); err != nil {
+		return nil, errors.Wrap(err, "invalid referrers index")
+	}
+	return index.Manifests, nil
+}
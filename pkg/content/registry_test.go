@@ -0,0 +1,597 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/artifact"
+	"oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/target"
+)
+
+var testManifest = []byte(`{"schemaVersion":2}`)
+
+// serveManifest serves the manifest as test:latest. The handler fails with
+// the status code in failure, if set, and counts the requests in hits.
+func serveManifest(manifest []byte, failure *int32, hits ...*int32) http.HandlerFunc {
+	dgst := digest.FromBytes(manifest)
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, h := range hits {
+			atomic.AddInt32(h, 1)
+		}
+		if failure != nil {
+			if status := atomic.LoadInt32(failure); status != 0 {
+				w.WriteHeader(int(status))
+				return
+			}
+		}
+		if r.URL.Path != "/v2/test/manifests/latest" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", dgst.String())
+		w.Write(manifest)
+	}
+}
+
+// newManifestServer creates a TLS server serving a single manifest. The server
+// listens on a non-loopback address, as loopback registries are accessed over
+// plain HTTP by default, and uses a self-signed certificate for that address.
+func newManifestServer(t *testing.T, dir string) (*httptest.Server, digest.Digest, string) {
+	var ip net.IP
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Fatalf("unable to list interface addresses: %v", err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			ip = ipNet.IP
+			break
+		}
+	}
+	if ip == nil {
+		t.Skip("no non-loopback address available")
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		t.Skipf("unable to listen on %s: %v", ip, err)
+	}
+
+	server := httptest.NewUnstartedServer(serveManifest(testManifest, nil))
+	server.Listener.Close()
+	server.Listener = listener
+
+	certificate, certFile, _ := generateCertificate(t, dir, "server", ip, x509.ExtKeyUsageServerAuth)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+	}
+	return server, digest.FromBytes(testManifest), certFile
+}
+
+// generateCertificate creates a self-signed certificate and writes it, with its
+// key, to the directory
+func generateCertificate(t *testing.T, dir, name string, ip net.IP, usage x509.ExtKeyUsage) (tls.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{usage},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unable to marshal key: %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	certFile := filepath.Join(dir, name+".pem")
+	if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		t.Fatalf("unable to write certificate: %v", err)
+	}
+	keyFile := filepath.Join(dir, name+".key")
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatalf("unable to write key: %v", err)
+	}
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("unable to load key pair: %v", err)
+	}
+	return certificate, certFile, keyFile
+}
+
+func TestRegistryTransport(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "oras_registry_test")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	defaultTransport := http.DefaultClient.Transport
+
+	server, dgst, caFile := newManifestServer(t, dir)
+	server.StartTLS()
+	defer server.Close()
+	ref := strings.TrimPrefix(server.URL, "https://") + "/test:latest"
+
+	// the server certificate is not trusted by default
+	registry, err := content.NewRegistry(content.RegistryOptions{})
+	if err != nil {
+		t.Fatalf("unable to create registry: %v", err)
+	}
+	if _, _, err := registry.Resolve(ctx, ref); err == nil {
+		t.Fatalf("expected resolve to fail on an untrusted certificate")
+	}
+
+	// trust the server through its CA file
+	registry, err = content.NewRegistry(content.RegistryOptions{
+		Transport: content.TransportOptions{
+			CAFiles:               []string{caFile},
+			DialTimeout:           5 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: 5 * time.Second,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to create registry: %v", err)
+	}
+	_, desc, err := registry.Resolve(ctx, ref)
+	if err != nil {
+		t.Fatalf("unable to resolve with CA file: %v", err)
+	}
+	if desc.Digest != dgst {
+		t.Fatalf("resolved %s, expected %s", desc.Digest, dgst)
+	}
+
+	// an insecure registry must not change the default client
+	if _, err := content.NewRegistry(content.RegistryOptions{Insecure: true}); err != nil {
+		t.Fatalf("unable to create registry: %v", err)
+	}
+	if http.DefaultClient.Transport != defaultTransport {
+		t.Fatalf("http.DefaultClient was modified")
+	}
+
+	// an invalid CA file is an error
+	if _, err := content.NewRegistry(content.RegistryOptions{
+		Transport: content.TransportOptions{CAFiles: []string{filepath.Join(dir, "missing.pem")}},
+	}); err == nil {
+		t.Fatalf("expected an error for a missing CA file")
+	}
+}
+
+func TestRegistryClientCertificate(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "oras_registry_test")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	clientCert, certFile, keyFile := generateCertificate(t, dir, "client", nil, x509.ExtKeyUsageClientAuth)
+	leaf, err := x509.ParseCertificate(clientCert.Certificate[0])
+	if err != nil {
+		t.Fatalf("unable to parse certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	server, dgst, caFile := newManifestServer(t, dir)
+	server.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	server.TLS.ClientCAs = pool
+	server.StartTLS()
+	defer server.Close()
+	ref := strings.TrimPrefix(server.URL, "https://") + "/test:latest"
+
+	registry, err := content.NewRegistry(content.RegistryOptions{
+		Transport: content.TransportOptions{CAFiles: []string{caFile}},
+	})
+	if err != nil {
+		t.Fatalf("unable to create registry: %v", err)
+	}
+	if _, _, err := registry.Resolve(ctx, ref); err == nil {
+		t.Fatalf("expected resolve to fail without a client certificate")
+	}
+
+	registry, err = content.NewRegistry(content.RegistryOptions{
+		Transport: content.TransportOptions{
+			CAFiles:  []string{caFile},
+			CertFile: certFile,
+			KeyFile:  keyFile,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to create registry: %v", err)
+	}
+	_, desc, err := registry.Resolve(ctx, ref)
+	if err != nil {
+		t.Fatalf("unable to resolve with client certificate: %v", err)
+	}
+	if desc.Digest != dgst {
+		t.Fatalf("resolved %s, expected %s", desc.Digest, dgst)
+	}
+}
+
+func TestRegistryHostsDir(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "oras_registry_test")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var upstreamHits, mirrorHits int32
+	upstream := httptest.NewServer(serveManifest(testManifest, nil, &upstreamHits))
+	defer upstream.Close()
+	brokenFailure := int32(http.StatusInternalServerError)
+	broken := httptest.NewServer(serveManifest(testManifest, &brokenFailure))
+	defer broken.Close()
+	var mirrorFailure int32
+	mirror := httptest.NewServer(serveManifest(testManifest, &mirrorFailure, &mirrorHits))
+	defer mirror.Close()
+
+	host := strings.TrimPrefix(upstream.URL, "http://")
+	hostDir := filepath.Join(dir, strings.Replace(host, ":", "_", 1)+"_")
+	if err := os.MkdirAll(hostDir, 0755); err != nil {
+		t.Fatalf("unable to create host dir: %v", err)
+	}
+	hostsToml := fmt.Sprintf(`server = "%s"
+
+[host."%s"]
+  capabilities = ["pull", "resolve"]
+
+[host."%s"]
+  capabilities = ["pull", "resolve"]
+`, upstream.URL, broken.URL, mirror.URL)
+	if err := ioutil.WriteFile(filepath.Join(hostDir, "hosts.toml"), []byte(hostsToml), 0644); err != nil {
+		t.Fatalf("unable to write hosts.toml: %v", err)
+	}
+
+	registry, err := content.NewRegistry(content.RegistryOptions{HostsDir: dir})
+	if err != nil {
+		t.Fatalf("unable to create registry: %v", err)
+	}
+	ref := host + "/test:latest"
+	expected := digest.FromBytes(testManifest)
+
+	// the broken mirror fails over to the next mirror
+	_, desc, err := registry.Resolve(ctx, ref)
+	if err != nil {
+		t.Fatalf("unable to resolve: %v", err)
+	}
+	if desc.Digest != expected {
+		t.Fatalf("resolved %s, expected %s", desc.Digest, expected)
+	}
+	if atomic.LoadInt32(&mirrorHits) == 0 || atomic.LoadInt32(&upstreamHits) != 0 {
+		t.Fatalf("expected the mirror to be used, got %d mirror and %d upstream requests", mirrorHits, upstreamHits)
+	}
+
+	// all mirrors failing falls back to the upstream
+	atomic.StoreInt32(&mirrorFailure, http.StatusServiceUnavailable)
+	if _, desc, err = registry.Resolve(ctx, ref); err != nil {
+		t.Fatalf("unable to resolve: %v", err)
+	}
+	if desc.Digest != expected {
+		t.Fatalf("resolved %s, expected %s", desc.Digest, expected)
+	}
+	if atomic.LoadInt32(&upstreamHits) == 0 {
+		t.Fatalf("expected the upstream to be used")
+	}
+
+	// the CA file of a host is trusted for that host
+	server, dgst, caFile := newManifestServer(t, dir)
+	server.StartTLS()
+	defer server.Close()
+	tlsHost := strings.TrimPrefix(server.URL, "https://")
+	tlsHostDir := filepath.Join(dir, strings.Replace(tlsHost, ":", "_", 1)+"_")
+	if err := os.MkdirAll(tlsHostDir, 0755); err != nil {
+		t.Fatalf("unable to create host dir: %v", err)
+	}
+	tlsHostsToml := fmt.Sprintf(`[host."%s"]
+  capabilities = ["pull", "resolve"]
+  ca = "%s"
+`, server.URL, caFile)
+	if err := ioutil.WriteFile(filepath.Join(tlsHostDir, "hosts.toml"), []byte(tlsHostsToml), 0644); err != nil {
+		t.Fatalf("unable to write hosts.toml: %v", err)
+	}
+	registry, err = content.NewRegistry(content.RegistryOptions{
+		HostsDir: dir,
+		Transport: content.TransportOptions{
+			DialTimeout: 5 * time.Second,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to create registry: %v", err)
+	}
+	if _, desc, err = registry.Resolve(ctx, tlsHost+"/test:latest"); err != nil {
+		t.Fatalf("unable to resolve with the CA file of the host: %v", err)
+	}
+	if desc.Digest != dgst {
+		t.Fatalf("resolved %s, expected %s", desc.Digest, dgst)
+	}
+}
+
+func TestRegistryList(t *testing.T) {
+	ctx := context.Background()
+	tags := []string{"v3", "v1", "v2", "latest", "v4"}
+	repositories := []string{"team/b", "team/a", "c"}
+
+	// serve the lists in pages of two items, with basic authentication
+	paginate := func(w http.ResponseWriter, r *http.Request, key string, items []string) {
+		n := 2
+		start := 0
+		if last := r.URL.Query().Get("last"); last != "" {
+			for i, item := range items {
+				if item == last {
+					start = i + 1
+				}
+			}
+		}
+		end := start + n
+		if end < len(items) {
+			next := *r.URL
+			next.RawQuery = url.Values{"n": {"2"}, "last": {items[end-1]}}.Encode()
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+		} else {
+			end = len(items)
+		}
+		json.NewEncoder(w).Encode(map[string][]string{key: items[start:end]})
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/team/a/tags/list":
+			paginate(w, r, "tags", tags)
+		case "/v2/_catalog":
+			paginate(w, r, "repositories", repositories)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	registry, err := content.NewRegistry(content.RegistryOptions{
+		Username: "user",
+		Password: "pass",
+	})
+	if err != nil {
+		t.Fatalf("unable to create registry: %v", err)
+	}
+	var lister target.Lister = registry
+
+	result, err := lister.Tags(ctx, host+"/team/a:latest")
+	if err != nil {
+		t.Fatalf("unable to list tags: %v", err)
+	}
+	if expected := []string{"latest", "v1", "v2", "v3", "v4"}; !reflect.DeepEqual(result, expected) {
+		t.Fatalf("listed tags %v, expected %v", result, expected)
+	}
+
+	result, err = lister.Repositories(ctx, host)
+	if err != nil {
+		t.Fatalf("unable to list repositories: %v", err)
+	}
+	if expected := []string{"c", "team/a", "team/b"}; !reflect.DeepEqual(result, expected) {
+		t.Fatalf("listed repositories %v, expected %v", result, expected)
+	}
+
+	if _, err := lister.Tags(ctx, host+"/missing"); !errors.Is(err, content.ErrNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestRegistryDelete(t *testing.T) {
+	ctx := context.Background()
+	manifestDigest := digest.FromBytes(testManifest)
+	blobDigest := digest.FromString("blob")
+
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/test/manifests/" + manifestDigest.String(), "/v2/test/blobs/" + blobDigest.String():
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		case "/v2/test/manifests/latest":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors":[{"code":"UNSUPPORTED","message":"tag deletion is not supported"}]}`))
+		case "/v2/test/manifests/v1":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	repository := strings.TrimPrefix(server.URL, "http://") + "/test"
+
+	registry, err := content.NewRegistry(content.RegistryOptions{})
+	if err != nil {
+		t.Fatalf("unable to create registry: %v", err)
+	}
+	var deleter target.Deleter = registry
+
+	if err := deleter.DeleteManifest(ctx, repository, manifestDigest); err != nil {
+		t.Fatalf("unable to delete manifest: %v", err)
+	}
+	if err := deleter.DeleteBlob(ctx, repository, blobDigest); err != nil {
+		t.Fatalf("unable to delete blob: %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("expected 2 deletions, got %v", deleted)
+	}
+	if err := deleter.DeleteBlob(ctx, repository, manifestDigest); !errors.Is(err, content.ErrNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	for _, ref := range []string{repository + ":latest", repository + ":v1"} {
+		if err := deleter.Untag(ctx, ref); !errors.Is(err, content.ErrUnsupported) {
+			t.Fatalf("expected unsupported error for %s, got %v", ref, err)
+		}
+	}
+	if err := deleter.Untag(ctx, repository); !errors.Is(err, content.ErrNoName) {
+		t.Fatalf("expected no name error, got %v", err)
+	}
+}
+
+func TestRegistryDiscover(t *testing.T) {
+	ctx := context.Background()
+	subject := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(testManifest),
+		Size:      int64(len(testManifest)),
+	}
+	referrers := []artifact.Descriptor{
+		{
+			Descriptor:   ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: digest.FromString("signature"), Size: 9},
+			ArtifactType: "application/vnd.example.signature",
+		},
+		{
+			Descriptor:   ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: digest.FromString("sbom"), Size: 4},
+			ArtifactType: "application/vnd.example.sbom",
+		},
+	}
+	index, err := json.Marshal(artifact.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: referrers,
+	})
+	if err != nil {
+		t.Fatalf("unable to marshal index: %v", err)
+	}
+	indexDigest := digest.FromBytes(index)
+
+	// the registry with the referrers API serves one referrer per page and
+	// ignores the artifactType filter
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/test/referrers/"+subject.Digest.String() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		page := referrers[:1]
+		if r.URL.Query().Get("page") == "2" {
+			page = referrers[1:]
+		} else {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=2>; rel="next"`, r.URL.Path))
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageIndex)
+		json.NewEncoder(w).Encode(artifact.Index{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			MediaType: ocispec.MediaTypeImageIndex,
+			Manifests: page,
+		})
+	}))
+	defer api.Close()
+
+	// the registry without the referrers API has the referrers tag
+	tagged := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/test/manifests/" + artifact.ReferrersTag(subject.Digest), "/v2/test/manifests/" + indexDigest.String():
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageIndex)
+			w.Header().Set("Docker-Content-Digest", indexDigest.String())
+			w.Header().Set("Content-Length", fmt.Sprint(len(index)))
+			if r.Method == http.MethodGet {
+				w.Write(index)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer tagged.Close()
+
+	// the registry without the referrers API nor the tag has no referrers
+	empty := httptest.NewServer(http.NotFoundHandler())
+	defer empty.Close()
+
+	registry, err := content.NewRegistry(content.RegistryOptions{})
+	if err != nil {
+		t.Fatalf("unable to create registry: %v", err)
+	}
+	var discoverer target.Discoverer = registry
+	for _, server := range []*httptest.Server{api, tagged} {
+		ref := strings.TrimPrefix(server.URL, "http://") + "/test"
+		result, err := discoverer.Discover(ctx, ref, subject, "")
+		if err != nil {
+			t.Fatalf("unable to discover from %s: %v", server.URL, err)
+		}
+		if !reflect.DeepEqual(result, referrers) {
+			t.Fatalf("discovered %v, expected %v", result, referrers)
+		}
+		result, err = discoverer.Discover(ctx, ref, subject, "application/vnd.example.sbom")
+		if err != nil {
+			t.Fatalf("unable to discover from %s: %v", server.URL, err)
+		}
+		if !reflect.DeepEqual(result, referrers[1:]) {
+			t.Fatalf("discovered %v, expected %v", result, referrers[1:])
+		}
+	}
+	result, err := discoverer.Discover(ctx, strings.TrimPrefix(empty.URL, "http://")+"/test", subject, "")
+	if err != nil {
+		t.Fatalf("unable to discover: %v", err)
+	}
+	if len(result) != 0 {
+		t.Fatalf("expected no referrers, got %v", result)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package content
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TransportOptions configure the HTTP transport used by a Registry
+type TransportOptions struct {
+	// CAFiles are PEM encoded CA bundles trusted in addition to the system roots
+	CAFiles []string
+	// CertFile and KeyFile are the PEM encoded client certificate and key
+	// presented for mutual TLS. KeyFile may be empty if CertFile holds both.
+	CertFile string
+	KeyFile  string
+	// ProxyURL is the URL of the proxy to go through. If empty, the proxy is
+	// taken from the environment.
+	ProxyURL string
+	// DialTimeout is the maximum time to establish a connection
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout is the maximum time to complete the TLS handshake
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout is the maximum time to wait for the response headers
+	// once the request is written
+	ResponseHeaderTimeout time.Duration
+}
+
+// newHTTPClient creates a new http.Client, with its own transport, according
+// to the registry options
+func newHTTPClient(opts RegistryOptions) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(opts.Transport, opts.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if err := configureTransport(transport, opts.Transport); err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: transport,
+	}, nil
+}
+
+// newTLSConfig creates the TLS configuration from the CA bundles and the client
+// certificate of the transport options
+func newTLSConfig(opts TransportOptions, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: insecure,
+	}
+	if len(opts.CAFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, caFile := range opts.CAFiles {
+			pem, err := ioutil.ReadFile(caFile)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.Errorf("no certificate found in CA file %q", caFile)
+			}
+		}
+		config.RootCAs = pool
+	}
+	if opts.CertFile != "" {
+		keyFile := opts.KeyFile
+		if keyFile == "" {
+			keyFile = opts.CertFile
+		}
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to load client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// configureTransport applies the proxy and the timeouts of the transport options
+func configureTransport(transport *http.Transport, opts TransportOptions) error {
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return errors.Wrap(err, "invalid proxy URL")
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if opts.DialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   opts.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	if opts.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = opts.TLSHandshakeTimeout
+	}
+	if opts.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package content
+
+import (
+	"context"
+	"io"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// VerifyingFetcher wraps a fetcher, such as the one of any target, to check the
+// content fetched against its descriptor as it is read. The readers never
+// return more than the size of the descriptor, and fail at EOF with
+// ErrSizeMismatch or ErrDigestMismatch if the content does not match.
+type VerifyingFetcher struct {
+	fetcher remotes.Fetcher
+}
+
+// NewVerifyingFetcher creates a fetcher verifying the content of fetcher
+func NewVerifyingFetcher(fetcher remotes.Fetcher) *VerifyingFetcher {
+	return &VerifyingFetcher{
+		fetcher: fetcher,
+	}
+}
+
+// Fetch get an io.ReadCloser for the specific content, verifying it as read
+func (f *VerifyingFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	if err := desc.Digest.Validate(); err != nil {
+		return nil, err
+	}
+	rc, err := f.fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	return NewVerifyingReader(rc, desc), nil
+}
+
+// verifyingReader hashes the content as read, and checks it at EOF
+type verifyingReader struct {
+	rc       io.ReadCloser
+	desc     ocispec.Descriptor
+	verifier digest.Verifier
+	size     int64
+	err      error
+}
+
+// NewVerifyingReader wraps the reader of the content of desc, so that it never
+// returns more than the size of desc, and fails at EOF with ErrSizeMismatch or
+// ErrDigestMismatch if the content does not match desc. The digest of desc must
+// be valid.
+func NewVerifyingReader(rc io.ReadCloser, desc ocispec.Descriptor) io.ReadCloser {
+	return &verifyingReader{
+		rc:       rc,
+		desc:     desc,
+		verifier: desc.Digest.Verifier(),
+	}
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	// read a byte more than the content left, to detect longer content
+	if left := r.desc.Size - r.size + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := r.rc.Read(p)
+	if r.size+int64(n) > r.desc.Size {
+		n = int(r.desc.Size - r.size)
+		r.err = errors.Wrapf(ErrSizeMismatch, "%s: content exceeds %d bytes", r.desc.Digest, r.desc.Size)
+		err = r.err
+	}
+	r.verifier.Write(p[:n])
+	r.size += int64(n)
+
+	if err == io.EOF {
+		switch {
+		case r.size != r.desc.Size:
+			r.err = errors.Wrapf(ErrSizeMismatch, "%s: got %d bytes, expected %d", r.desc.Digest, r.size, r.desc.Size)
+		case !r.verifier.Verified():
+			r.err = errors.Wrap(ErrDigestMismatch, r.desc.Digest.String())
+		default:
+			r.err = io.EOF
+		}
+		err = r.err
+	}
+	return n, err
+}
+
+func (r *verifyingReader) Close() error {
+	return r.rc.Close()
+}
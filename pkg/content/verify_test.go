@@ -0,0 +1,80 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package content_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/content"
+)
+
+func TestVerifyingFetcher(t *testing.T) {
+	ctx := context.Background()
+	memoryStore := content.NewMemory()
+	desc, err := memoryStore.Add("hello.txt", "", testContent)
+	if err != nil {
+		t.Fatalf("unexpected error adding content: %v", err)
+	}
+
+	// serve fixed content whatever the descriptor
+	serve := func(p []byte) remotes.Fetcher {
+		return remotes.FetcherFunc(func(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(p)), nil
+		})
+	}
+	corrupt := append([]byte{}, testContent...)
+	corrupt[0] = 'J'
+	long := append(append([]byte{}, testContent...), '!')
+	tests := []struct {
+		name    string
+		fetcher remotes.Fetcher
+		err     error
+		read    []byte
+	}{
+		{"matching content", memoryStore, nil, testContent},
+		{"corrupt content", serve(corrupt), content.ErrDigestMismatch, corrupt},
+		{"short content", serve(testContent[:5]), content.ErrSizeMismatch, testContent[:5]},
+		{"long content", serve(long), content.ErrSizeMismatch, testContent},
+	}
+	for _, tt := range tests {
+		rc, err := content.NewVerifyingFetcher(tt.fetcher).Fetch(ctx, desc)
+		if err != nil {
+			t.Fatalf("%s: unexpected error fetching: %v", tt.name, err)
+		}
+		read, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got error %v, expected %v", tt.name, err, tt.err)
+		}
+		if !bytes.Equal(read, tt.read) {
+			t.Errorf("%s: read %q, expected %q", tt.name, read, tt.read)
+		}
+	}
+
+	invalid := desc
+	invalid.Digest = digest.Digest("sha256:invalid")
+	if _, err := content.NewVerifyingFetcher(memoryStore).Fetch(ctx, invalid); err == nil {
+		t.Error("expected error fetching an invalid digest")
+	}
+}
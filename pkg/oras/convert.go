@@ -0,0 +1,369 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oras
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	orascontent "oras.land/oras-go/pkg/content"
+)
+
+// dockerToOCI maps the Docker schema2 media types to their OCI equivalents
+var dockerToOCI = map[string]string{
+	images.MediaTypeDockerSchema2Manifest:         ocispec.MediaTypeImageManifest,
+	images.MediaTypeDockerSchema2ManifestList:     ocispec.MediaTypeImageIndex,
+	images.MediaTypeDockerSchema2Config:           ocispec.MediaTypeImageConfig,
+	images.MediaTypeDockerSchema2Layer:            ocispec.MediaTypeImageLayer,
+	images.MediaTypeDockerSchema2LayerGzip:        ocispec.MediaTypeImageLayerGzip,
+	images.MediaTypeDockerSchema2LayerForeign:     ocispec.MediaTypeImageLayerNonDistributable,
+	images.MediaTypeDockerSchema2LayerForeignGzip: ocispec.MediaTypeImageLayerNonDistributableGzip,
+}
+
+// ociToDocker maps the OCI media types to their Docker schema2 equivalents
+var ociToDocker = invertMapping(dockerToOCI)
+
+// invertMapping returns the mapping of the values back to their keys
+func invertMapping(mapping map[string]string) map[string]string {
+	inverted := make(map[string]string, len(mapping))
+	for from, to := range mapping {
+		inverted[to] = from
+	}
+	return inverted
+}
+
+// layerMediaTypes lists the media types of each kind of tar layer by compression
+var layerMediaTypes = []map[orascontent.Compression]string{
+	{
+		orascontent.CompressionNone: ocispec.MediaTypeImageLayer,
+		orascontent.CompressionGzip: ocispec.MediaTypeImageLayerGzip,
+		orascontent.CompressionZstd: ocispec.MediaTypeImageLayer + "+zstd",
+	},
+	{
+		orascontent.CompressionNone: ocispec.MediaTypeImageLayerNonDistributable,
+		orascontent.CompressionGzip: ocispec.MediaTypeImageLayerNonDistributableGzip,
+		orascontent.CompressionZstd: ocispec.MediaTypeImageLayerNonDistributable + "+zstd",
+	},
+	{
+		orascontent.CompressionNone: images.MediaTypeDockerSchema2Layer,
+		orascontent.CompressionGzip: images.MediaTypeDockerSchema2LayerGzip,
+	},
+	{
+		orascontent.CompressionNone: images.MediaTypeDockerSchema2LayerForeign,
+		orascontent.CompressionGzip: images.MediaTypeDockerSchema2LayerForeignGzip,
+	},
+}
+
+// recompressedMediaType returns the media type of the layer once recompressed,
+// or false if the media type is not one of a tar layer. Other blobs, such as
+// the files of artifacts, are not recompressed.
+func recompressedMediaType(mediaType string, c orascontent.Compression) (string, bool, error) {
+	for _, mediaTypes := range layerMediaTypes {
+		for _, layerMediaType := range mediaTypes {
+			if layerMediaType != mediaType {
+				continue
+			}
+			recompressed, ok := mediaTypes[c]
+			if !ok {
+				return "", false, errors.Wrapf(ErrUnsupportedCompression, "%s: %s", mediaType, c)
+			}
+			return recompressed, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// recompressLayer fetches the layer, recompresses it to a temporary file, and
+// pushes it to the store with the media type. Returns the new descriptor.
+func recompressLayer(ctx context.Context, desc ocispec.Descriptor, fetcher remotes.Fetcher, store orascontent.Store, c orascontent.Compression, mediaType string) (ocispec.Descriptor, error) {
+	file, err := ioutil.TempFile("", "oras_recompress_")
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	digester := digest.Canonical.Digester()
+	cw, err := orascontent.NewRecompressWriter(orascontent.NewIoContentWriter(io.MultiWriter(file, digester.Hash())), c)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer cw.Close()
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer rc.Close()
+	if err := content.Copy(ctx, cw, rc, desc.Size, desc.Digest); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if cw.Digest() != desc.Digest {
+		return ocispec.Descriptor{}, errors.Wrapf(errdefs.ErrFailedPrecondition, "unexpected commit digest %s, expected %s", cw.Digest(), desc.Digest)
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	newDesc := desc
+	newDesc.MediaType = mediaType
+	newDesc.Digest = digester.Digest()
+	newDesc.Size = size
+	w, err := store.Push(ctx, newDesc)
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			return newDesc, nil
+		}
+		return ocispec.Descriptor{}, err
+	}
+	defer w.Close()
+	if err := content.Copy(ctx, w, file, newDesc.Size, newDesc.Digest); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return newDesc, nil
+}
+
+// manifestRewriter rewrites the manifests copied, converting their media types
+// and running the manifest hooks on them
+type manifestRewriter struct {
+	store   orascontent.Store
+	fetcher remotes.Fetcher
+	mapping map[string]string
+	hooks   []ManifestHook
+	// state records the manifests and layers rewritten, and the blobs pushed
+	// by the hooks, under the lock
+	lock  *sync.Mutex
+	state *copyState
+}
+
+// rewriteManifests rewrites the media types of the manifests, and of the
+// descriptors they hold, according to the mapping, then runs the hooks. The
+// manifests are given as seen from the root, so they are rewritten in reverse
+// order and a parent refers to the new digests of its children. The rewritten
+// manifests are written to the store and recorded in converted. The
+// descriptors of the manifests are returned in the same order. Manifests
+// without anything to rewrite are left untouched.
+func (r *manifestRewriter) rewriteManifests(ctx context.Context, manifests []ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	done := make(map[digest.Digest]ocispec.Descriptor, len(manifests))
+	result := make([]ocispec.Descriptor, len(manifests))
+	for i := len(manifests) - 1; i >= 0; i-- {
+		desc := manifests[i]
+		if newDesc, ok := done[desc.Digest]; ok {
+			result[i] = newDesc
+			continue
+		}
+		newDesc, err := r.rewriteManifest(ctx, desc)
+		if err != nil {
+			return nil, err
+		}
+		done[desc.Digest] = newDesc
+		if newDesc.Digest != desc.Digest || newDesc.MediaType != desc.MediaType {
+			r.state.converted[desc.Digest] = newDesc
+		}
+		result[i] = newDesc
+	}
+	return result, nil
+}
+
+// rewriteManifest rewrites a single manifest, given the ones rewritten so far
+func (r *manifestRewriter) rewriteManifest(ctx context.Context, desc ocispec.Descriptor) (ocispec.Descriptor, error) {
+	original, err := content.ReadBlob(ctx, &ProviderWrapper{Fetcher: r.store}, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	p, changed, err := r.convertManifest(desc, original)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	newDesc := desc
+	if mediaType, ok := r.mapping[desc.MediaType]; ok {
+		newDesc.MediaType = mediaType
+	}
+	if len(r.hooks) > 0 {
+		store := &hookStore{Fetcher: r.fetcher, Pusher: &pushRecorder{Pusher: r.store, lock: r.lock, state: r.state}}
+		for _, hook := range r.hooks {
+			if p, err = hook(ctx, newDesc, p, store); err != nil {
+				return ocispec.Descriptor{}, err
+			}
+		}
+		changed = !bytes.Equal(p, original)
+	}
+	if !changed {
+		return newDesc, nil
+	}
+	newDesc.Digest = digest.FromBytes(p)
+	newDesc.Size = int64(len(p))
+
+	w, err := r.store.Push(ctx, newDesc)
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			return newDesc, nil
+		}
+		return ocispec.Descriptor{}, err
+	}
+	defer w.Close()
+	if err := content.Copy(ctx, w, bytes.NewReader(p), newDesc.Size, newDesc.Digest); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return newDesc, nil
+}
+
+// convertManifest converts the media types of a manifest and points it to the
+// descriptors converted so far. Returns the manifest, and whether it changed.
+func (r *manifestRewriter) convertManifest(desc ocispec.Descriptor, p []byte) ([]byte, bool, error) {
+	var manifest map[string]json.RawMessage
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		return nil, false, err
+	}
+
+	changed, err := convertMediaType(manifest, r.mapping)
+	if err != nil {
+		return nil, false, err
+	}
+	// Docker manifests require the media type, which is optional in OCI
+	if _, ok := manifest["mediaType"]; !ok {
+		if mediaType, ok := r.mapping[desc.MediaType]; ok {
+			if manifest["mediaType"], err = json.Marshal(mediaType); err != nil {
+				return nil, false, err
+			}
+			changed = true
+		}
+	}
+	for _, key := range []string{"config", "layers", "manifests", "blobs", "subject"} {
+		value, ok := manifest[key]
+		if !ok {
+			continue
+		}
+		var newValue json.RawMessage
+		var updated bool
+		if key == "config" || key == "subject" {
+			newValue, updated, err = convertDescriptor(value, r.mapping, r.state.converted)
+		} else {
+			newValue, updated, err = convertDescriptors(value, r.mapping, r.state.converted)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if updated {
+			manifest[key] = newValue
+			changed = true
+		}
+	}
+	if !changed {
+		return p, false, nil
+	}
+	p, err = json.Marshal(manifest)
+	return p, true, err
+}
+
+// convertDescriptors converts a JSON array of descriptors
+func convertDescriptors(value json.RawMessage, mapping map[string]string, converted map[digest.Digest]ocispec.Descriptor) (json.RawMessage, bool, error) {
+	var descs []json.RawMessage
+	if err := json.Unmarshal(value, &descs); err != nil {
+		return nil, false, err
+	}
+	var changed bool
+	for i, desc := range descs {
+		newDesc, updated, err := convertDescriptor(desc, mapping, converted)
+		if err != nil {
+			return nil, false, err
+		}
+		if updated {
+			descs[i] = newDesc
+			changed = true
+		}
+	}
+	if !changed {
+		return value, false, nil
+	}
+	p, err := json.Marshal(descs)
+	return p, true, err
+}
+
+// convertDescriptor converts a JSON descriptor, keeping the fields it does not
+// know about, such as the platform or the urls
+func convertDescriptor(value json.RawMessage, mapping map[string]string, converted map[digest.Digest]ocispec.Descriptor) (json.RawMessage, bool, error) {
+	var desc map[string]json.RawMessage
+	if err := json.Unmarshal(value, &desc); err != nil {
+		return nil, false, err
+	}
+	changed, err := convertMediaType(desc, mapping)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var dgst digest.Digest
+	if raw, ok := desc["digest"]; ok {
+		if err := json.Unmarshal(raw, &dgst); err != nil {
+			return nil, false, err
+		}
+	}
+	if newDesc, ok := converted[dgst]; ok {
+		if desc["mediaType"], err = json.Marshal(newDesc.MediaType); err != nil {
+			return nil, false, err
+		}
+		if desc["digest"], err = json.Marshal(newDesc.Digest); err != nil {
+			return nil, false, err
+		}
+		if desc["size"], err = json.Marshal(newDesc.Size); err != nil {
+			return nil, false, err
+		}
+		changed = true
+	}
+	if !changed {
+		return value, false, nil
+	}
+	p, err := json.Marshal(desc)
+	return p, true, err
+}
+
+// convertMediaType rewrites the mediaType field of a JSON object, and reports
+// whether it was changed
+func convertMediaType(object map[string]json.RawMessage, mapping map[string]string) (bool, error) {
+	raw, ok := object["mediaType"]
+	if !ok {
+		return false, nil
+	}
+	var mediaType string
+	if err := json.Unmarshal(raw, &mediaType); err != nil {
+		return false, err
+	}
+	newMediaType, ok := mapping[mediaType]
+	if !ok || newMediaType == mediaType {
+		return false, nil
+	}
+	p, err := json.Marshal(newMediaType)
+	if err != nil {
+		return false, err
+	}
+	object["mediaType"] = p
+	return true, nil
+}
@@ -17,6 +17,7 @@ package oras
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 
@@ -24,8 +25,12 @@ import (
 	"github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/images"
 	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/reference"
 	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/pkg/artifact"
+	orascontent "oras.land/oras-go/pkg/content"
 	"oras.land/oras-go/pkg/target"
 )
 
@@ -57,34 +62,168 @@ func Copy(ctx context.Context, from target.Target, fromRef string, to target.Tar
 		return ocispec.Descriptor{}, ErrToResolverUndefined
 	}
 
+	return copyRoot(ctx, from, fromRef, to, toRef, opt)
+}
+
+// copyRoot resolves fromRef and copies the graph of its root to toRef, without
+// validating the arguments
+func copyRoot(ctx context.Context, from target.Target, fromRef string, to target.Target, toRef string, opt *copyOpts) (ocispec.Descriptor, error) {
 	// for the "from", we resolve the ref, then use resolver.Fetcher to fetch the various content blobs
 	// for the "to", we simply use resolver.Pusher to push the various content blobs
 
+	if opt.policy != nil {
+		if err := opt.policy.CheckReference(ctx, fromRef); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
 	_, desc, err := from.Resolve(ctx, fromRef)
 	if err != nil {
 		return ocispec.Descriptor{}, err
 	}
+	if opt.verifySource != nil {
+		if err := opt.verifySource(ctx, from, fromRef, desc); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
 
 	fetcher, err := from.Fetcher(ctx, fromRef)
 	if err != nil {
 		return ocispec.Descriptor{}, err
 	}
-	// construct the reference we send to the pusher using the digest, so it knows what the root is
-	pushRef := fmt.Sprintf("%s@%s", toRef, desc.Digest.String())
-	pusher, err := to.Pusher(ctx, pushRef)
+	state := &copyState{
+		converted: make(map[digest.Digest]ocispec.Descriptor),
+		counted:   make(map[digest.Digest]bool),
+	}
+	root, manifests, err := transferContent(ctx, desc, fetcher, rootPusher(ctx, to, toRef, !opt.untagged), state, opt)
 	if err != nil {
 		return ocispec.Descriptor{}, err
 	}
+	if opt.referrers {
+		if err := copyReferrers(ctx, from, fromRef, fetcher, to, repositoryOf(toRef), manifests, state, opt); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	if opt.verifyDestination {
+		if err := verifyDestination(ctx, to, toRef, root, state.pushed, !opt.skipManifest && !opt.untagged); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	if opt.saveDescriptors != nil {
+		opt.saveDescriptors(state.converted)
+	}
+	return root, nil
+}
 
-	if err := transferContent(ctx, desc, fetcher, pusher, opt); err != nil {
-		return ocispec.Descriptor{}, err
+// copyState is shared by the transfers of a copy, such as the ones of the root
+// and of its referrers
+type copyState struct {
+	// converted records the manifests and layers rewritten by their original
+	// digest, which also lets referrers point to their rewritten subject
+	converted map[digest.Digest]ocispec.Descriptor
+	// pushed records the descriptors pushed to the destination
+	pushed []ocispec.Descriptor
+	// size is the total size of the distinct descriptors copied
+	size    int64
+	counted map[digest.Digest]bool
+}
+
+// rootPusher returns a func creating a pusher for the root, which may differ
+// from the source root when the manifests are converted. The root is tagged
+// with ref unless tag is false.
+func rootPusher(ctx context.Context, to target.Target, ref string, tag bool) func(ocispec.Descriptor) (remotes.Pusher, error) {
+	return func(root ocispec.Descriptor) (remotes.Pusher, error) {
+		// construct the reference we send to the pusher using the digest, so it knows what the root is
+		ref := fmt.Sprintf("%s@%s", ref, root.Digest.String())
+		// the stores tagging the root with the reference are asked not to,
+		// while registries do not tag a root pushed by digest
+		if untagged, ok := to.(target.UntaggedPusher); ok && !tag {
+			return untagged.UntaggedPusher(ctx, ref)
+		}
+		return to.Pusher(ctx, ref)
+	}
+}
+
+// repositoryOf returns the repository of the reference, or an empty string for
+// references without a repository, such as the plain tags of a Memory store
+func repositoryOf(ref string) string {
+	spec, err := reference.Parse(ref)
+	if err != nil {
+		return ""
 	}
-	return desc, nil
+	return spec.Locator
 }
 
-func transferContent(ctx context.Context, desc ocispec.Descriptor, fetcher remotes.Fetcher, pusher remotes.Pusher, opts *copyOpts) error {
+// copyReferrers copies the referrers of the manifests, and recursively their
+// own referrers, to the repository of the destination without tagging them.
+// The referrers index of the destination is updated if it keeps one. The
+// manifests converted so far are used to point the referrers to their
+// converted subjects.
+func copyReferrers(ctx context.Context, from target.Target, fromRef string, fetcher remotes.Fetcher, to target.Target, repository string, manifests []ocispec.Descriptor, state *copyState, opt *copyOpts) error {
+	discoverer, ok := from.(target.Discoverer)
+	if !ok {
+		return ErrDiscoverUnsupported
+	}
+	indexer, _ := to.(target.ReferrerIndexer)
+
+	// the referrers must not replace the root for the callers of the options
+	referrerOpt := *opt
+	referrerOpt.saveManifest = nil
+	referrerOpt.saveLayers = nil
+
+	visited := make(map[digest.Digest]bool, len(manifests))
+	for _, manifest := range manifests {
+		visited[manifest.Digest] = true
+	}
+	for len(manifests) > 0 {
+		subject := manifests[0]
+		manifests = manifests[1:]
+		referrers, err := discoverer.Discover(ctx, fromRef, subject, "")
+		if err != nil {
+			return err
+		}
+		for _, referrer := range referrers {
+			if visited[referrer.Digest] || !isAllowedArtifactType(referrer.ArtifactType, opt.referrerTypes...) {
+				continue
+			}
+			visited[referrer.Digest] = true
+
+			root, _, err := transferContent(ctx, referrer.Descriptor, fetcher, rootPusher(ctx, to, repository, false), state, &referrerOpt)
+			if err != nil {
+				return err
+			}
+			if indexer != nil {
+				pushedSubject, ok := state.converted[subject.Digest]
+				if !ok {
+					pushedSubject = subject
+				}
+				pushed := referrer
+				pushed.Descriptor = root
+				if err := indexer.IndexReferrer(ctx, repository, pushedSubject, pushed); err != nil {
+					return err
+				}
+			}
+			manifests = append(manifests, referrer.Descriptor)
+		}
+	}
+	return nil
+}
+
+// transferContent copies the graph of desc, and returns the root pushed with
+// the manifests copied from the source. The descriptors rewritten and pushed on
+// the way are recorded in the state.
+func transferContent(ctx context.Context, desc ocispec.Descriptor, fetcher remotes.Fetcher, newPusher func(ocispec.Descriptor) (remotes.Pusher, error), state *copyState, opts *copyOpts) (ocispec.Descriptor, []ocispec.Descriptor, error) {
+	pusher, err := newPusher(desc)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	// with size limits, the content read must not exceed the sizes checked
+	if opts.maxManifestSize > 0 || opts.maxBlobSize > 0 || opts.maxArtifactSize > 0 {
+		fetcher = orascontent.NewVerifyingFetcher(fetcher)
+	}
+
 	var descriptors, manifests []ocispec.Descriptor
 	lock := &sync.Mutex{}
+	configs := &sync.Map{} // map[digest.Digest]bool
 	picker := images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
 		if isAllowedMediaType(desc.MediaType, opts.allowedMediaTypes...) {
 			if opts.filterName(desc) {
@@ -102,7 +241,7 @@ func transferContent(ctx context.Context, desc ocispec.Descriptor, fetcher remot
 	// 2. so that we can retrieve them to analyze and find children in the Dispatch routine
 	store := opts.contentProvideIngesterPusherFetcher
 	if store == nil {
-		store = newHybridStoreFromPusher(pusher, opts.cachedMediaTypes, true)
+		store = newHybridStoreFromPusher(pusher, opts.storeCachedMediaTypes(), true)
 	}
 
 	// fetchHandler pushes to the *store*, which may or may not cache it
@@ -133,44 +272,115 @@ func transferContent(ctx context.Context, desc ocispec.Descriptor, fetcher remot
 			lock.Lock()
 			defer lock.Unlock()
 			manifests = append(manifests, desc)
+			return baseFetchHandler(store, fetcher)(ctx, desc)
 		}
+		if opts.layerCompression != "" {
+			// the layers are recompressed with the media type they are converted to
+			mediaType, ok := opts.mediaTypeMapping[desc.MediaType]
+			if !ok {
+				mediaType = desc.MediaType
+			}
+			recompressed, ok, err := recompressedMediaType(mediaType, opts.layerCompression)
+			if err != nil {
+				return nil, err
+			}
+			if ok && recompressed != mediaType {
+				newDesc, err := recompressLayer(ctx, desc, fetcher, store, opts.layerCompression, recompressed)
+				if err != nil {
+					return nil, err
+				}
+				lock.Lock()
+				defer lock.Unlock()
+				state.converted[desc.Digest] = newDesc
+				state.pushed = append(state.pushed, newDesc)
+				return nil, nil
+			}
+		}
+		lock.Lock()
+		state.pushed = append(state.pushed, desc)
+		lock.Unlock()
 		return baseFetchHandler(store, fetcher)(ctx, desc)
 	})
 
 	handlers := []images.Handler{
-		filterHandler(opts, opts.allowedMediaTypes...),
+		filterHandler(opts, configs, opts.allowedMediaTypes...),
 	}
 	handlers = append(handlers, opts.baseHandlers...)
 	handlers = append(handlers,
+		images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+			if opts.policy != nil {
+				if err := opts.policy.CheckDescriptor(ctx, desc); err != nil {
+					return nil, err
+				}
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			return nil, opts.checkSizeLimits(desc, state)
+		}),
 		fetchHandler,
+		policyHandler(opts, store),
 		picker,
-		images.ChildrenHandler(&ProviderWrapper{Fetcher: store}),
+		configHandler(childrenHandler(&ProviderWrapper{Fetcher: store}), configs),
 	)
 	handlers = append(handlers, opts.callbackHandlers...)
 
 	if err := opts.dispatch(ctx, images.Handlers(handlers...), nil, desc); err != nil {
-		return err
+		return ocispec.Descriptor{}, nil, err
+	}
+
+	// the layers saved are the ones pushed
+	for i, layer := range descriptors {
+		if newDesc, ok := state.converted[layer.Digest]; ok {
+			descriptors[i] = newDesc
+		}
+	}
+
+	// rewrite the cached manifests, the root is then pushed under its new digest
+	root, pushed := desc, manifests
+	if opts.mediaTypeMapping != nil || opts.layerCompression != "" || len(opts.manifestHooks) > 0 {
+		rewriter := &manifestRewriter{
+			store:   store,
+			fetcher: fetcher,
+			mapping: opts.mediaTypeMapping,
+			hooks:   opts.manifestHooks,
+			lock:    lock,
+			state:   state,
+		}
+		if pushed, err = rewriter.rewriteManifests(ctx, manifests); err != nil {
+			return ocispec.Descriptor{}, nil, err
+		}
+		if len(pushed) > 0 && manifests[0].Digest == desc.Digest {
+			root = pushed[0]
+		}
+		if root.Digest != desc.Digest {
+			if pusher, err = newPusher(root); err != nil {
+				return ocispec.Descriptor{}, nil, err
+			}
+		}
 	}
 
 	// we cached all of the manifests, so push those out
 	// Iterate in reverse order as seen, parent always uploaded after child
-	for i := len(manifests) - 1; i >= 0; i-- {
-		_, err := baseFetchHandler(pusher, store)(ctx, manifests[i])
-		if err != nil {
-			return err
+	if !opts.skipManifest {
+		for i := len(pushed) - 1; i >= 0; i-- {
+			_, err := baseFetchHandler(pusher, store)(ctx, pushed[i])
+			if err != nil {
+				return ocispec.Descriptor{}, nil, err
+			}
+			state.pushed = append(state.pushed, pushed[i])
 		}
 	}
 
 	// if the option to request the root manifest was passed, accommodate it
-	if opts.saveManifest != nil && len(manifests) > 0 {
-		rc, err := store.Fetch(ctx, manifests[0])
+	if opts.saveManifest != nil && len(pushed) > 0 {
+		rc, err := store.Fetch(ctx, pushed[0])
 		if err != nil {
-			return fmt.Errorf("could not get root manifest to save based on CopyOpt: %v", err)
+			return ocispec.Descriptor{}, nil, fmt.Errorf("could not get root manifest to save based on CopyOpt: %v", err)
 		}
 		defer rc.Close()
 		buf := new(bytes.Buffer)
 		if _, err := buf.ReadFrom(rc); err != nil {
-			return fmt.Errorf("unable to read data for root manifest to save based on CopyOpt: %v", err)
+			return ocispec.Descriptor{}, nil, fmt.Errorf("unable to read data for root manifest to save based on CopyOpt: %v", err)
 		}
 		// get the root manifest from the store
 		opts.saveManifest(buf.Bytes())
@@ -180,19 +390,24 @@ func transferContent(ctx context.Context, desc ocispec.Descriptor, fetcher remot
 	if opts.saveLayers != nil && len(descriptors) > 0 {
 		opts.saveLayers(descriptors)
 	}
-	return nil
+	return root, manifests, nil
 }
 
-func filterHandler(opts *copyOpts, allowedMediaTypes ...string) images.HandlerFunc {
+func filterHandler(opts *copyOpts, configs *sync.Map, allowedMediaTypes ...string) images.HandlerFunc {
 	return func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
 		switch {
-		case isAllowedMediaType(desc.MediaType, ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex):
+		case orascontent.IsManifestMediaType(desc.MediaType):
 			return nil, nil
 		case isAllowedMediaType(desc.MediaType, allowedMediaTypes...):
-			if opts.filterName(desc) {
+			if !opts.filterName(desc) {
+				log.G(ctx).Warnf("blob no name: %v", desc.Digest)
+				break
+			}
+			_, isConfig := configs.Load(desc.Digest)
+			if opts.filterFile(desc, isConfig) {
 				return nil, nil
 			}
-			log.G(ctx).Warnf("blob no name: %v", desc.Digest)
+			log.G(ctx).Debugf("blob filtered out: %v", desc.Digest)
 		default:
 			log.G(ctx).Warnf("unknown type: %v", desc.MediaType)
 		}
@@ -200,6 +415,72 @@ func filterHandler(opts *copyOpts, allowedMediaTypes ...string) images.HandlerFu
 	}
 }
 
+// policyHandler checks the content of the manifests cached against the policy
+// of the options, before their children are copied
+func policyHandler(opts *copyOpts, store orascontent.Store) images.HandlerFunc {
+	return func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if opts.policy == nil || !isAllowedMediaType(desc.MediaType, opts.cachedMediaTypes...) {
+			return nil, nil
+		}
+		p, err := content.ReadBlob(ctx, &ProviderWrapper{Fetcher: store}, desc)
+		if err != nil {
+			return nil, err
+		}
+		return nil, opts.policy.CheckManifest(ctx, desc, p)
+	}
+}
+
+// childrenHandler returns the children of the manifests, indexes and artifact
+// manifests. The blobs are the children of an artifact manifest, its subject
+// is not.
+func childrenHandler(provider content.Provider) images.HandlerFunc {
+	children := images.ChildrenHandler(provider)
+	return func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if desc.MediaType != artifact.MediaTypeArtifactManifest {
+			return children(ctx, desc)
+		}
+		p, err := content.ReadBlob(ctx, provider, desc)
+		if err != nil {
+			return nil, err
+		}
+		var manifest artifact.Manifest
+		if err := json.Unmarshal(p, &manifest); err != nil {
+			return nil, err
+		}
+		return manifest.Blobs, nil
+	}
+}
+
+// configHandler wraps a children handler to record the config of each image
+// manifest, so that configs can be told apart from layers when filtering.
+func configHandler(f images.HandlerFunc, configs *sync.Map) images.HandlerFunc {
+	return func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		children, err := f(ctx, desc)
+		if err != nil {
+			return nil, err
+		}
+		switch desc.MediaType {
+		case ocispec.MediaTypeImageManifest, images.MediaTypeDockerSchema2Manifest:
+			if len(children) > 0 {
+				configs.Store(children[0].Digest, true)
+			}
+		}
+		return children, nil
+	}
+}
+
+func isAllowedArtifactType(artifactType string, allowedArtifactTypes ...string) bool {
+	if len(allowedArtifactTypes) == 0 {
+		return true
+	}
+	for _, allowedArtifactType := range allowedArtifactTypes {
+		if artifactType == allowedArtifactType {
+			return true
+		}
+	}
+	return false
+}
+
 func isAllowedMediaType(mediaType string, allowedMediaTypes ...string) bool {
 	if len(allowedMediaTypes) == 0 {
 		return true
@@ -0,0 +1,719 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oras
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/containerd/containerd/archive/compression"
+	ctrcontent "github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"oras.land/oras-go/pkg/artifact"
+	orascontent "oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/policy"
+	"oras.land/oras-go/pkg/target"
+)
+
+type CopyTestSuite struct {
+	suite.Suite
+	ref        string
+	store      *orascontent.Memory
+	configDesc ocispec.Descriptor
+	files      map[string]string
+}
+
+func (suite *CopyTestSuite) SetupTest() {
+	suite.ref = "localhost:5000/copy:test"
+	suite.files = map[string]string{
+		"docs/readme.md": "read me",
+		"docs/notes.txt": "some notes",
+		"bin/tool":       "binary",
+		"docs/design.md": "design",
+	}
+	suite.store = orascontent.NewMemory()
+	var descs []ocispec.Descriptor
+	for name, content := range suite.files {
+		desc, err := suite.store.Add(name, "", []byte(content))
+		suite.Nil(err, "no error adding file")
+		descs = append(descs, desc)
+	}
+	manifest, manifestDesc, config, configDesc, err := orascontent.GenerateManifestAndConfig(nil, nil, descs...)
+	suite.Nil(err, "no error generating manifest")
+	suite.store.Set(configDesc, config)
+	err = suite.store.StoreManifest(suite.ref, manifestDesc, manifest)
+	suite.Nil(err, "no error storing manifest")
+	suite.configDesc = configDesc
+}
+
+func (suite *CopyTestSuite) Test_0_FilePatterns() {
+	ctx := context.Background()
+
+	_, err := Copy(ctx, suite.store, suite.ref, orascontent.NewMemory(), "", WithPullFilePatterns("["))
+	suite.NotNil(err, "error with malformed pattern")
+
+	to := orascontent.NewMemory()
+	_, err = Copy(ctx, suite.store, suite.ref, to, "", WithPullFilePatterns("docs/*.md", "bin/tool"))
+	suite.Nil(err, "no error copying with file patterns")
+	for name, content := range suite.files {
+		_, actual, ok := to.GetByName(name)
+		if name == "docs/notes.txt" {
+			suite.False(ok, "%s filtered out", name)
+			continue
+		}
+		suite.True(ok, "%s copied", name)
+		suite.Equal([]byte(content), actual, "%s content matches", name)
+	}
+	_, _, ok := to.Get(suite.configDesc)
+	suite.True(ok, "config copied")
+	_, _, err = to.Resolve(ctx, suite.ref)
+	suite.Nil(err, "manifest copied")
+}
+
+func (suite *CopyTestSuite) Test_1_SkipConfigAndManifest() {
+	ctx := context.Background()
+
+	to := orascontent.NewMemory()
+	_, err := Copy(ctx, suite.store, suite.ref, to, "", WithPullFilePatterns("docs/readme.md"), WithPullSkipConfig(), WithPullSkipManifest())
+	suite.Nil(err, "no error copying with skipped config and manifest")
+	_, _, ok := to.GetByName("docs/readme.md")
+	suite.True(ok, "matching file copied")
+	_, _, ok = to.GetByName("docs/design.md")
+	suite.False(ok, "other file filtered out")
+	_, _, ok = to.Get(suite.configDesc)
+	suite.False(ok, "config skipped")
+	_, _, err = to.Resolve(ctx, suite.ref)
+	suite.NotNil(err, "manifest skipped")
+}
+
+func (suite *CopyTestSuite) Test_2_Referrers() {
+	ctx := context.Background()
+
+	_, subjectDesc, err := suite.store.Resolve(ctx, suite.ref)
+	suite.Nil(err, "no error resolving subject")
+
+	// attach a signature and an SBOM to the manifest, and a signature to the SBOM
+	refer := func(name string, subject ocispec.Descriptor, artifactType string) ocispec.Descriptor {
+		config, configDesc, err := orascontent.GenerateEmptyConfig()
+		suite.Nil(err, "no error generating empty config")
+		suite.store.Set(configDesc, config)
+		desc, err := suite.store.Add(name, "", []byte(name))
+		suite.Nil(err, "no error adding %s", name)
+		manifest, manifestDesc, err := orascontent.GenerateManifestWithOpts(nil, nil, []ocispec.Descriptor{desc},
+			orascontent.WithSubject(subject),
+			orascontent.WithArtifactType(artifactType),
+		)
+		suite.Nil(err, "no error generating %s manifest", name)
+		suite.store.Set(manifestDesc, manifest)
+		return manifestDesc
+	}
+	signature := refer("signature", subjectDesc, "application/vnd.example.signature")
+	sbom := refer("sbom", subjectDesc, "application/vnd.example.sbom")
+	sbomSignature := refer("sbom-signature", sbom, "application/vnd.example.signature")
+
+	_, err = Copy(ctx, &targetOnly{suite.store}, suite.ref, orascontent.NewMemory(), "", WithReferrers())
+	suite.Equal(ErrDiscoverUnsupported, err, "error copying referrers from a target without discovery")
+
+	to := orascontent.NewMemory()
+	_, err = Copy(ctx, suite.store, suite.ref, to, "", WithReferrers())
+	suite.Nil(err, "no error copying with referrers")
+	for _, desc := range []ocispec.Descriptor{signature, sbom, sbomSignature} {
+		_, _, ok := to.Get(desc)
+		suite.True(ok, "referrer %s copied", desc.Digest)
+	}
+	_, desc, err := to.Resolve(ctx, suite.ref)
+	suite.Nil(err, "root still tagged")
+	suite.Equal(subjectDesc.Digest, desc.Digest, "root not replaced by a referrer")
+
+	// filtering on the signatures does not reach the signature of the SBOM
+	to = orascontent.NewMemory()
+	_, err = Copy(ctx, suite.store, suite.ref, to, "", WithReferrers("application/vnd.example.signature"))
+	suite.Nil(err, "no error copying with filtered referrers")
+	_, _, ok := to.Get(signature)
+	suite.True(ok, "signature copied")
+	_, _, ok = to.Get(sbom)
+	suite.False(ok, "SBOM filtered out")
+	_, _, ok = to.Get(sbomSignature)
+	suite.False(ok, "signature of the SBOM not reached")
+
+	// a repository reference tags the root, and only the root, in the
+	// Memory and OCI stores
+	const repository = "localhost:5000/repo"
+	dir, err := ioutil.TempDir("", "oras_copy_test")
+	suite.Nil(err, "no error creating temp dir")
+	defer os.RemoveAll(dir)
+	ociStore, err := orascontent.NewOCI(dir)
+	suite.Nil(err, "no error creating OCI store")
+	for _, to := range []target.Target{orascontent.NewMemory(), ociStore} {
+		_, err = Copy(ctx, suite.store, suite.ref, to, repository, WithReferrers())
+		suite.Nil(err, "no error copying to a repository reference")
+		_, desc, err = to.Resolve(ctx, repository)
+		suite.Nil(err, "root tagged with the repository reference")
+		suite.Equal(subjectDesc.Digest, desc.Digest, "root not replaced by a referrer")
+	}
+}
+
+func (suite *CopyTestSuite) Test_3_ArtifactManifest() {
+	ctx := context.Background()
+
+	_, subjectDesc, err := suite.store.Resolve(ctx, suite.ref)
+	suite.Nil(err, "no error resolving subject")
+	blob, err := suite.store.Add("sbom.json", "application/vnd.example.sbom.v1+json", []byte("{}"))
+	suite.Nil(err, "no error adding blob")
+	manifest, manifestDesc, err := orascontent.GenerateArtifactManifest("application/vnd.example.sbom", nil, []ocispec.Descriptor{blob},
+		orascontent.WithSubject(subjectDesc),
+	)
+	suite.Nil(err, "no error generating artifact manifest")
+	ref := "localhost:5000/copy:sbom"
+	err = suite.store.StoreManifest(ref, manifestDesc, manifest)
+	suite.Nil(err, "no error storing artifact manifest")
+
+	// the blobs of the artifact manifest are copied and the manifest tagged
+	to := orascontent.NewMemory()
+	_, err = Copy(ctx, suite.store, ref, to, "")
+	suite.Nil(err, "no error copying artifact manifest")
+	_, actual, ok := to.GetByName("sbom.json")
+	suite.True(ok, "blob copied")
+	suite.Equal([]byte("{}"), actual, "blob content matches")
+	_, desc, err := to.Resolve(ctx, ref)
+	suite.Nil(err, "artifact manifest tagged")
+	suite.Equal(manifestDesc.Digest, desc.Digest, "artifact manifest copied")
+	_, _, ok = to.Get(subjectDesc)
+	suite.False(ok, "subject not copied as a child")
+
+	tempDir, err := ioutil.TempDir("", "oras_copy_test")
+	suite.Nil(err, "no error creating temp dir")
+	defer os.RemoveAll(tempDir)
+	ociStore, err := orascontent.NewOCI(tempDir)
+	suite.Nil(err, "no error creating OCI store")
+	_, err = Copy(ctx, suite.store, ref, ociStore, "sbom")
+	suite.Nil(err, "no error copying artifact manifest to OCI layout")
+	suite.Equal(manifestDesc.Digest, ociStore.ListReferences()["sbom"].Digest, "artifact manifest tagged in OCI layout")
+
+	// the artifact manifest is copied as a referrer of its subject
+	to = orascontent.NewMemory()
+	_, err = Copy(ctx, suite.store, suite.ref, to, "", WithReferrers("application/vnd.example.sbom"))
+	suite.Nil(err, "no error copying with referrers")
+	_, _, ok = to.Get(manifestDesc)
+	suite.True(ok, "artifact manifest copied as referrer")
+}
+
+func (suite *CopyTestSuite) Test_4_DockerManifest() {
+	ctx := context.Background()
+	ref := "localhost:5000/copy:docker"
+	listDesc, manifestDesc, configDesc, layer := storeDockerImage(suite.Assertions, suite.store, ref)
+
+	// the Docker manifests are copied and tagged as is
+	tempDir, err := ioutil.TempDir("", "oras_copy_test")
+	suite.Nil(err, "no error creating temp dir")
+	defer os.RemoveAll(tempDir)
+	ociStore, err := orascontent.NewOCI(tempDir)
+	suite.Nil(err, "no error creating OCI store")
+	desc, err := Copy(ctx, suite.store, ref, ociStore, "docker")
+	suite.Nil(err, "no error copying manifest list to OCI layout")
+	suite.Equal(listDesc.Digest, desc.Digest, "manifest list not converted")
+	suite.Equal(listDesc.Digest, ociStore.ListReferences()["docker"].Digest, "manifest list tagged in OCI layout")
+	_, err = ociStore.Info(ctx, layer.Digest)
+	suite.Nil(err, "layer copied to OCI layout")
+
+	// the converted root is tagged, and refers to the converted manifest
+	to := orascontent.NewMemory()
+	desc, err = Copy(ctx, suite.store, ref, to, "", WithConvertToOCI())
+	suite.Nil(err, "no error copying with conversion")
+	suite.Equal(ocispec.MediaTypeImageIndex, desc.MediaType, "root converted to an index")
+	suite.NotEqual(listDesc.Digest, desc.Digest, "converted root has a new digest")
+	_, tagged, err := to.Resolve(ctx, ref)
+	suite.Nil(err, "converted root tagged")
+	suite.Equal(desc.Digest, tagged.Digest, "tag points to the converted root")
+
+	_, content, ok := to.Get(desc)
+	suite.True(ok, "index copied")
+	var index artifact.Index
+	suite.Nil(json.Unmarshal(content, &index), "no error parsing index")
+	suite.Equal(ocispec.MediaTypeImageIndex, index.MediaType, "index media type converted")
+	suite.Len(index.Manifests, 1, "index keeps its manifest")
+	suite.Equal(ocispec.MediaTypeImageManifest, index.Manifests[0].MediaType, "manifest descriptor converted")
+	suite.Equal(manifestDesc.Platform, index.Manifests[0].Platform, "platform kept")
+
+	_, content, ok = to.Get(index.Manifests[0].Descriptor)
+	suite.True(ok, "converted manifest copied")
+	var converted artifact.ImageManifest
+	suite.Nil(json.Unmarshal(content, &converted), "no error parsing manifest")
+	suite.Equal(ocispec.MediaTypeImageManifest, converted.MediaType, "manifest media type converted")
+	suite.Equal(ocispec.MediaTypeImageConfig, converted.Config.MediaType, "config media type converted")
+	suite.Equal(configDesc.Digest, converted.Config.Digest, "config content unchanged")
+	suite.Equal(ocispec.MediaTypeImageLayerGzip, converted.Layers[0].MediaType, "layer media type converted")
+	suite.Equal(layer.Annotations, converted.Layers[0].Annotations, "layer annotations kept")
+	_, actual, ok := to.GetByName("layer.tar.gz")
+	suite.True(ok, "layer copied")
+	suite.Equal([]byte("layer"), actual, "layer content unchanged")
+}
+
+func (suite *CopyTestSuite) Test_5_MediaTypeMapping() {
+	ctx := context.Background()
+	ref := "localhost:5000/copy:mapping"
+	listDesc, manifestDesc, _, _ := storeDockerImage(suite.Assertions, suite.store, ref)
+
+	_, err := Copy(ctx, suite.store, ref, orascontent.NewMemory(), "", WithDescriptorMapping(nil))
+	suite.NotNil(err, "error with nil descriptor mapping func")
+
+	// a referrer of the manifest list points to the converted root
+	config, configDesc, err := orascontent.GenerateEmptyConfig()
+	suite.Nil(err, "no error generating empty config")
+	suite.store.Set(configDesc, config)
+	signature, err := suite.store.Add("signature", "", []byte("signature"))
+	suite.Nil(err, "no error adding signature")
+	referrer, referrerDesc, err := orascontent.GenerateManifestWithOpts(nil, nil, []ocispec.Descriptor{signature},
+		orascontent.WithSubject(listDesc),
+		orascontent.WithArtifactType("application/vnd.example.signature"),
+	)
+	suite.Nil(err, "no error generating referrer")
+	suite.store.Set(referrerDesc, referrer)
+
+	var mapping map[digest.Digest]ocispec.Descriptor
+	to := orascontent.NewMemory()
+	root, err := Copy(ctx, suite.store, ref, to, "", WithConvertToOCI(), WithReferrers(), WithDescriptorMapping(func(m map[digest.Digest]ocispec.Descriptor) {
+		mapping = m
+	}))
+	suite.Nil(err, "no error copying with conversion and referrers")
+	suite.Equal(root, mapping[listDesc.Digest], "root mapped")
+	suite.Equal(ocispec.MediaTypeImageManifest, mapping[manifestDesc.Digest].MediaType, "manifest mapped")
+	convertedReferrer, ok := mapping[referrerDesc.Digest]
+	suite.True(ok, "referrer mapped")
+	_, content, ok := to.Get(convertedReferrer)
+	suite.True(ok, "referrer copied")
+	var manifest artifact.ImageManifest
+	suite.Nil(json.Unmarshal(content, &manifest), "no error parsing referrer")
+	suite.Equal(root.Digest, manifest.Subject.Digest, "referrer points to the converted root")
+	suite.Equal(ocispec.MediaTypeImageIndex, manifest.Subject.MediaType, "subject media type converted")
+	referrers, err := to.Discover(ctx, "", root, "")
+	suite.Nil(err, "no error discovering converted root")
+	suite.Len(referrers, 1, "converted root has its referrer")
+
+	// converting back to Docker restores the media types
+	back := orascontent.NewMemory()
+	root, err = Copy(ctx, to, ref, back, "", WithConvertToDocker())
+	suite.Nil(err, "no error converting back to Docker")
+	suite.Equal(images.MediaTypeDockerSchema2ManifestList, root.MediaType, "root converted back")
+	_, content, ok = back.Get(root)
+	suite.True(ok, "manifest list copied")
+	var index artifact.Index
+	suite.Nil(json.Unmarshal(content, &index), "no error parsing manifest list")
+	suite.Equal(images.MediaTypeDockerSchema2ManifestList, index.MediaType, "manifest list media type restored")
+	_, content, ok = back.Get(index.Manifests[0].Descriptor)
+	suite.True(ok, "manifest copied")
+	suite.Nil(json.Unmarshal(content, &manifest), "no error parsing manifest")
+	suite.Equal(images.MediaTypeDockerSchema2Manifest, manifest.MediaType, "manifest media type restored")
+	suite.Equal(images.MediaTypeDockerSchema2Config, manifest.Config.MediaType, "config media type restored")
+	suite.Equal(images.MediaTypeDockerSchema2LayerGzip, manifest.Layers[0].MediaType, "layer media type restored")
+
+	// custom mappings rewrite the layers only
+	to = orascontent.NewMemory()
+	root, err = Copy(ctx, suite.store, ref, to, "", WithMediaTypeMapping(map[string]string{
+		images.MediaTypeDockerSchema2LayerGzip: "application/vnd.example.layer",
+	}))
+	suite.Nil(err, "no error copying with a custom mapping")
+	suite.Equal(images.MediaTypeDockerSchema2ManifestList, root.MediaType, "root media type kept")
+	suite.NotEqual(listDesc.Digest, root.Digest, "root refers to the rewritten manifest")
+
+	// manifests mapped to custom media types are cached and pushed
+	const customManifest = "application/vnd.example.manifest.v1+json"
+	to = orascontent.NewMemory()
+	root, err = Copy(ctx, suite.store, ref, to, "", WithMediaTypeMapping(map[string]string{
+		images.MediaTypeDockerSchema2Manifest: customManifest,
+	}))
+	suite.Nil(err, "no error copying with a custom manifest mapping")
+	_, content, ok = to.Get(root)
+	suite.True(ok, "manifest list copied")
+	suite.Nil(json.Unmarshal(content, &index), "no error parsing manifest list")
+	suite.Equal(customManifest, index.Manifests[0].MediaType, "manifest media type mapped")
+	_, content, ok = to.Get(index.Manifests[0].Descriptor)
+	suite.True(ok, "mapped manifest copied")
+	suite.Nil(json.Unmarshal(content, &manifest), "no error parsing mapped manifest")
+	suite.Equal(customManifest, manifest.MediaType, "mapped manifest media type rewritten")
+}
+
+func (suite *CopyTestSuite) Test_6_LayerCompression() {
+	ctx := context.Background()
+	ref := "localhost:5000/copy:compression"
+	listDesc, manifestDesc, _, layer := storeDockerImage(suite.Assertions, suite.store, ref)
+
+	_, err := Copy(ctx, suite.store, ref, orascontent.NewMemory(), "", WithLayerCompression("lz4"))
+	suite.NotNil(err, "error with unsupported compression")
+	_, err = Copy(ctx, suite.store, ref, orascontent.NewMemory(), "", WithLayerCompression(orascontent.CompressionZstd))
+	suite.True(errors.Is(err, ErrUnsupportedCompression), "error compressing Docker layers with zstd")
+
+	var mapping map[digest.Digest]ocispec.Descriptor
+	to := orascontent.NewMemory()
+	root, err := Copy(ctx, suite.store, ref, to, "", WithConvertToOCI(), WithLayerCompression(orascontent.CompressionZstd), WithDescriptorMapping(func(m map[digest.Digest]ocispec.Descriptor) {
+		mapping = m
+	}))
+	suite.Nil(err, "no error copying with recompression")
+	suite.Equal(root, mapping[listDesc.Digest], "root mapped")
+	recompressed, ok := mapping[layer.Digest]
+	suite.True(ok, "layer mapped")
+	suite.Equal(ocispec.MediaTypeImageLayer+"+zstd", recompressed.MediaType, "layer media type rewritten")
+	suite.Equal(layer.Annotations, recompressed.Annotations, "layer annotations kept")
+
+	_, content, ok := to.Get(mapping[manifestDesc.Digest])
+	suite.True(ok, "manifest copied")
+	var manifest artifact.ImageManifest
+	suite.Nil(json.Unmarshal(content, &manifest), "no error parsing manifest")
+	suite.Equal(recompressed, manifest.Layers[0], "manifest refers to the recompressed layer")
+	_, content, ok = to.Get(recompressed)
+	suite.True(ok, "recompressed layer copied")
+	suite.Equal(recompressed.Digest, digest.FromBytes(content), "recompressed layer matches its digest")
+	r, err := compression.DecompressStream(bytes.NewReader(content))
+	suite.Nil(err, "no error decompressing layer")
+	defer r.Close()
+	suite.Equal(compression.Zstd, r.GetCompression(), "layer compressed with zstd")
+	actual, err := ioutil.ReadAll(r)
+	suite.Nil(err, "no error reading layer")
+	suite.Equal([]byte("layer"), actual, "layer content unchanged")
+	_, _, ok = to.Get(layer)
+	suite.False(ok, "original layer not copied")
+
+	// the layers already compressed are copied as is
+	to = orascontent.NewMemory()
+	root, err = Copy(ctx, suite.store, ref, to, "", WithLayerCompression(orascontent.CompressionGzip))
+	suite.Nil(err, "no error copying with the same compression")
+	suite.Equal(listDesc.Digest, root.Digest, "root unchanged")
+	_, _, ok = to.Get(layer)
+	suite.True(ok, "original layer copied")
+}
+
+func (suite *CopyTestSuite) Test_7_ManifestHook() {
+	ctx := context.Background()
+	_, subjectDesc, err := suite.store.Resolve(ctx, suite.ref)
+	suite.Nil(err, "no error resolving manifest")
+
+	_, err = Copy(ctx, suite.store, suite.ref, orascontent.NewMemory(), "", WithManifestHook(nil))
+	suite.NotNil(err, "error with nil hook")
+	hookErr := errors.New("hook failed")
+	_, err = Copy(ctx, suite.store, suite.ref, orascontent.NewMemory(), "", WithManifestHook(func(context.Context, ocispec.Descriptor, []byte, orascontent.Store) ([]byte, error) {
+		return nil, hookErr
+	}))
+	suite.Equal(hookErr, err, "hook error returned")
+
+	// annotations stamped on the manifest
+	annotations := map[string]string{"org.example.promoted.from": "localhost:5000/copy"}
+	to := orascontent.NewMemory()
+	root, err := Copy(ctx, suite.store, suite.ref, to, "", WithManifestAnnotations(annotations))
+	suite.Nil(err, "no error copying with annotations")
+	suite.NotEqual(subjectDesc.Digest, root.Digest, "annotated root has a new digest")
+	_, tagged, err := to.Resolve(ctx, suite.ref)
+	suite.Nil(err, "annotated root tagged")
+	suite.Equal(root.Digest, tagged.Digest, "tag points to the annotated root")
+	_, content, ok := to.Get(root)
+	suite.True(ok, "annotated manifest copied")
+	var manifest artifact.ImageManifest
+	suite.Nil(json.Unmarshal(content, &manifest), "no error parsing manifest")
+	suite.Equal(annotations, manifest.Annotations, "annotations stamped")
+	suite.Equal(suite.configDesc.Digest, manifest.Config.Digest, "config unchanged")
+
+	// a hook rewriting the config
+	rewriteConfig := func(ctx context.Context, desc ocispec.Descriptor, p []byte, store orascontent.Store) ([]byte, error) {
+		var manifest artifact.ImageManifest
+		if err := json.Unmarshal(p, &manifest); err != nil {
+			return nil, err
+		}
+		config, err := ctrcontent.ReadBlob(ctx, &ProviderWrapper{Fetcher: store}, manifest.Config)
+		if err != nil {
+			return nil, err
+		}
+		config = append(config, ' ')
+		manifest.Config.Digest = digest.FromBytes(config)
+		manifest.Config.Size = int64(len(config))
+		w, err := store.Push(ctx, manifest.Config)
+		if err != nil {
+			return nil, err
+		}
+		defer w.Close()
+		if err := ctrcontent.Copy(ctx, w, bytes.NewReader(config), manifest.Config.Size, manifest.Config.Digest); err != nil {
+			return nil, err
+		}
+		return json.Marshal(manifest)
+	}
+	to = orascontent.NewMemory()
+	root, err = Copy(ctx, suite.store, suite.ref, to, "", WithManifestHook(rewriteConfig), WithManifestAnnotations(annotations))
+	suite.Nil(err, "no error copying with a config hook")
+	_, content, ok = to.Get(root)
+	suite.True(ok, "rewritten manifest copied")
+	suite.Nil(json.Unmarshal(content, &manifest), "no error parsing manifest")
+	suite.Equal(annotations, manifest.Annotations, "hooks run in order")
+	_, config, ok := to.Get(manifest.Config)
+	suite.True(ok, "rewritten config copied")
+	suite.Equal(manifest.Config.Digest, digest.FromBytes(config), "rewritten config matches its digest")
+
+	// the config pushed by the hook is verified at the destination
+	faulty := &faultyTarget{Memory: orascontent.NewMemory(), corrupt: manifest.Config.Digest}
+	_, err = Copy(ctx, suite.store, suite.ref, faulty, "", WithManifestHook(rewriteConfig), WithManifestAnnotations(annotations), WithDestinationVerification())
+	var verr *VerificationError
+	suite.True(errors.As(err, &verr), "verification error with a corrupt rewritten config: %v", err)
+	if verr != nil {
+		suite.Len(verr.Discrepancies, 1, "one discrepancy")
+		suite.Equal(manifest.Config.Digest, verr.Discrepancies[0].Descriptor.Digest, "rewritten config reported")
+	}
+
+	// the index refers to the annotated manifest
+	ref := "localhost:5000/copy:hook"
+	listDesc, manifestDesc, _, _ := storeDockerImage(suite.Assertions, suite.store, ref)
+	var mapping map[digest.Digest]ocispec.Descriptor
+	to = orascontent.NewMemory()
+	root, err = Copy(ctx, suite.store, ref, to, "", WithManifestAnnotations(annotations), WithDescriptorMapping(func(m map[digest.Digest]ocispec.Descriptor) {
+		mapping = m
+	}))
+	suite.Nil(err, "no error copying manifest list with annotations")
+	suite.Equal(mapping[listDesc.Digest], root, "root mapped")
+	suite.Equal(images.MediaTypeDockerSchema2ManifestList, root.MediaType, "root media type kept")
+	_, content, ok = to.Get(root)
+	suite.True(ok, "annotated manifest list copied")
+	var index artifact.Index
+	suite.Nil(json.Unmarshal(content, &index), "no error parsing manifest list")
+	suite.Equal(annotations, index.Annotations, "manifest list annotated")
+	suite.Equal(mapping[manifestDesc.Digest].Digest, index.Manifests[0].Digest, "manifest list refers to the annotated manifest")
+	suite.Equal(manifestDesc.Platform, index.Manifests[0].Platform, "platform kept")
+}
+
+func (suite *CopyTestSuite) Test_8_DestinationVerification() {
+	ctx := context.Background()
+	_, root, err := suite.store.Resolve(ctx, suite.ref)
+	suite.Nil(err, "no error resolving manifest")
+	readme, _, ok := suite.store.GetByName("docs/readme.md")
+	suite.True(ok, "readme found")
+
+	_, err = Copy(ctx, suite.store, suite.ref, orascontent.NewMemory(), "", WithDestinationVerification())
+	suite.Nil(err, "no error verifying a faithful destination")
+	_, err = Copy(ctx, suite.store, suite.ref, orascontent.NewMemory(), "", WithPullFilePatterns("docs/*"), WithPullSkipManifest(), WithDestinationVerification())
+	suite.Nil(err, "no error verifying the content filtered out")
+
+	to := &faultyTarget{
+		Memory:  orascontent.NewMemory(),
+		missing: readme.Digest,
+		corrupt: suite.configDesc.Digest,
+		moved:   true,
+	}
+	_, err = Copy(ctx, suite.store, suite.ref, to, "", WithDestinationVerification())
+	verr, ok := err.(*VerificationError)
+	suite.True(ok, "verification error returned")
+	suite.Equal(suite.ref, verr.Ref, "verified ref")
+	suite.Len(verr.Discrepancies, 3, "discrepancies found")
+	discrepancies := make(map[digest.Digest]error)
+	for _, d := range verr.Discrepancies {
+		discrepancies[d.Descriptor.Digest] = d.Err
+	}
+	suite.True(errors.Is(discrepancies[root.Digest], ErrRootMismatch), "root mismatch reported")
+	suite.True(errors.Is(discrepancies[readme.Digest], orascontent.ErrNotFound), "missing blob reported")
+	suite.True(errors.Is(discrepancies[suite.configDesc.Digest], ErrDigestMismatch), "corrupt blob reported")
+
+	// the plain tags of the Memory and OCI stores are checked as well
+	for _, ref := range []string{"ref", "test:v1"} {
+		_, err = Copy(ctx, suite.store, suite.ref, &faultyTarget{Memory: orascontent.NewMemory(), moved: true}, ref, WithDestinationVerification())
+		verr, ok = err.(*VerificationError)
+		suite.True(ok, "verification error returned for %s", ref)
+		if ok {
+			suite.Len(verr.Discrepancies, 1, "root mismatch found for %s", ref)
+			suite.True(errors.Is(verr.Discrepancies[0].Err, ErrRootMismatch), "root mismatch reported for %s", ref)
+		}
+	}
+	_, err = Copy(ctx, suite.store, suite.ref, &faultyTarget{Memory: orascontent.NewMemory(), moved: true}, "test@"+root.Digest.String(), WithDestinationVerification())
+	suite.Nil(err, "no root check for a digest reference")
+}
+
+func (suite *CopyTestSuite) Test_9_SizeLimits() {
+	ctx := context.Background()
+	_, root, err := suite.store.Resolve(ctx, suite.ref)
+	suite.Nil(err, "no error resolving manifest")
+	total := root.Size + suite.configDesc.Size
+	for _, content := range suite.files {
+		total += int64(len(content))
+	}
+
+	for _, opt := range []CopyOpt{WithMaxManifestSize(0), WithMaxBlobSize(-1), WithMaxArtifactSize(0)} {
+		_, err = Copy(ctx, suite.store, suite.ref, orascontent.NewMemory(), "", opt)
+		suite.NotNil(err, "error with non-positive limit")
+	}
+
+	tests := []struct {
+		name string
+		opts []CopyOpt
+		err  error
+	}{
+		{"manifest too large", []CopyOpt{WithMaxManifestSize(root.Size - 1)}, ErrSizeLimitExceeded},
+		{"blob too large", []CopyOpt{WithMaxBlobSize(int64(len("some notes")) - 1)}, ErrSizeLimitExceeded},
+		{"artifact too large", []CopyOpt{WithMaxArtifactSize(total - 1)}, ErrSizeLimitExceeded},
+		{"large blob filtered out", []CopyOpt{WithMaxBlobSize(int64(len("read me"))), WithPullFilePatterns("docs/readme.md")}, nil},
+		{"within limits", []CopyOpt{WithMaxManifestSize(root.Size), WithMaxBlobSize(int64(len("some notes"))), WithMaxArtifactSize(total)}, nil},
+	}
+	for _, tt := range tests {
+		_, err := Copy(ctx, suite.store, suite.ref, orascontent.NewMemory(), "", tt.opts...)
+		suite.True(errors.Is(err, tt.err), "%s: got error %v", tt.name, err)
+	}
+
+	// a blob larger than its descriptor tells is not read past its size
+	blob := []byte("larger than told")
+	blobDesc, err := suite.store.Add("blob", "", blob)
+	suite.Nil(err, "no error adding blob")
+	blobDesc.Size = 2
+	manifest, manifestDesc, config, configDesc, err := orascontent.GenerateManifestAndConfig(nil, nil, blobDesc)
+	suite.Nil(err, "no error generating manifest")
+	suite.store.Set(configDesc, config)
+	ref := "localhost:5000/copy:limits"
+	err = suite.store.StoreManifest(ref, manifestDesc, manifest)
+	suite.Nil(err, "no error storing manifest")
+	_, err = Copy(ctx, suite.store, ref, orascontent.NewMemory(), "", WithMaxBlobSize(int64(len(blob))))
+	suite.True(errors.Is(err, orascontent.ErrSizeMismatch), "error reading past the size told: %v", err)
+}
+
+func (suite *CopyTestSuite) Test_10_Policy() {
+	ctx := context.Background()
+	readme, _, ok := suite.store.GetByName("docs/readme.md")
+	suite.True(ok, "readme found")
+
+	_, err := Copy(ctx, suite.store, suite.ref, orascontent.NewMemory(), "", WithPolicy(nil))
+	suite.NotNil(err, "error with nil policy")
+
+	tests := []struct {
+		name  string
+		rules policy.RuleSet
+		rule  string
+		desc  *ocispec.Descriptor
+	}{
+		{"registry allowed", policy.RuleSet{AllowedRegistries: []string{"localhost:5000"}}, "", nil},
+		{"registry not allowed", policy.RuleSet{AllowedRegistries: []string{"registry.example.com"}}, policy.RuleAllowedRegistries, nil},
+		{"media type denied", policy.RuleSet{DeniedMediaTypes: []string{suite.configDesc.MediaType}}, policy.RuleDeniedMediaTypes, &suite.configDesc},
+		{"layer too large", policy.RuleSet{MaxLayerSize: readme.Size - 1}, policy.RuleMaxLayerSize, nil},
+		{"annotation missing", policy.RuleSet{RequiredAnnotations: []string{"org.example.pipeline"}}, policy.RuleRequiredAnnotations, nil},
+	}
+	for _, tt := range tests {
+		rules := tt.rules
+		to := orascontent.NewMemory()
+		_, err := Copy(ctx, suite.store, suite.ref, to, "", WithPolicy(&rules))
+		if tt.rule == "" {
+			suite.Nil(err, "%s: no error", tt.name)
+			continue
+		}
+		violation, ok := err.(*policy.Violation)
+		if !suite.True(ok, "%s: violation returned, got %v", tt.name, err) {
+			continue
+		}
+		suite.Equal(tt.rule, violation.Rule, "%s: rule violated", tt.name)
+		if tt.desc != nil {
+			suite.Equal(tt.desc.Digest, violation.Descriptor.Digest, "%s: descriptor named", tt.name)
+		}
+		_, _, err = to.Resolve(ctx, suite.ref)
+		suite.NotNil(err, "%s: manifest not pushed", tt.name)
+	}
+
+	// the blobs filtered out are not evaluated
+	rules := policy.RuleSet{MaxLayerSize: readme.Size}
+	_, err = Copy(ctx, suite.store, suite.ref, orascontent.NewMemory(), "", WithPolicy(&rules), WithPullFilePatterns("docs/readme.md"))
+	suite.Nil(err, "no error with the larger blobs filtered out")
+}
+
+// storeDockerImage stores a Docker manifest list of a single image under ref
+func storeDockerImage(a *assert.Assertions, store *orascontent.Memory, ref string) (listDesc, manifestDesc, configDesc, layer ocispec.Descriptor) {
+	var err error
+	layer, err = store.Add("layer.tar.gz", images.MediaTypeDockerSchema2LayerGzip, []byte("layer"))
+	a.Nil(err, "no error adding layer")
+	config := []byte("{}")
+	configDesc = ocispec.Descriptor{
+		MediaType: images.MediaTypeDockerSchema2Config,
+		Digest:    digest.FromBytes(config),
+		Size:      int64(len(config)),
+	}
+	store.Set(configDesc, config)
+	manifest, err := json.Marshal(artifact.ImageManifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: images.MediaTypeDockerSchema2Manifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layer},
+	})
+	a.Nil(err, "no error marshaling manifest")
+	manifestDesc = ocispec.Descriptor{
+		MediaType: images.MediaTypeDockerSchema2Manifest,
+		Digest:    digest.FromBytes(manifest),
+		Size:      int64(len(manifest)),
+		Platform:  &ocispec.Platform{OS: "linux", Architecture: "amd64"},
+	}
+	store.Set(manifestDesc, manifest)
+	list, err := json.Marshal(artifact.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: images.MediaTypeDockerSchema2ManifestList,
+		Manifests: []artifact.Descriptor{{Descriptor: manifestDesc}},
+	})
+	a.Nil(err, "no error marshaling manifest list")
+	listDesc = ocispec.Descriptor{
+		MediaType: images.MediaTypeDockerSchema2ManifestList,
+		Digest:    digest.FromBytes(list),
+		Size:      int64(len(list)),
+	}
+	err = store.StoreManifest(ref, listDesc, list)
+	a.Nil(err, "no error storing manifest list")
+	return listDesc, manifestDesc, configDesc, layer
+}
+
+// targetOnly hides all the capabilities of a target but target.Target
+type targetOnly struct {
+	target.Target
+}
+
+// faultyTarget serves altered content for some digests, as a destination that
+// lost or corrupted content after accepting it
+type faultyTarget struct {
+	*orascontent.Memory
+	missing digest.Digest
+	corrupt digest.Digest
+	moved   bool
+}
+
+func (t *faultyTarget) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	name, desc, err := t.Memory.Resolve(ctx, ref)
+	if t.moved {
+		desc.Digest = digest.FromString("moved")
+	}
+	return name, desc, err
+}
+
+func (t *faultyTarget) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	return t, nil
+}
+
+func (t *faultyTarget) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	switch desc.Digest {
+	case t.missing:
+		return nil, orascontent.ErrNotFound
+	case t.corrupt:
+		return ioutil.NopCloser(bytes.NewReader(make([]byte, desc.Size))), nil
+	}
+	return t.Memory.Fetch(ctx, desc)
+}
+
+func TestCopyTestSuite(t *testing.T) {
+	suite.Run(t, new(CopyTestSuite))
+}
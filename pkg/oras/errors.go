@@ -18,15 +18,29 @@ package oras
 import (
 	"errors"
 	"fmt"
+
+	orascontent "oras.land/oras-go/pkg/content"
 )
 
 // Common errors
 var (
-	ErrResolverUndefined     = errors.New("resolver undefined")
-	ErrFromResolverUndefined = errors.New("from target resolver undefined")
-	ErrToResolverUndefined   = errors.New("to target resolver undefined")
-	ErrFromTargetUndefined   = errors.New("from target undefined")
-	ErrToTargetUndefined     = errors.New("from target undefined")
+	ErrResolverUndefined      = errors.New("resolver undefined")
+	ErrFromResolverUndefined  = errors.New("from target resolver undefined")
+	ErrToResolverUndefined    = errors.New("to target resolver undefined")
+	ErrFromTargetUndefined    = errors.New("from target undefined")
+	ErrToTargetUndefined      = errors.New("from target undefined")
+	ErrNoSubject              = errors.New("manifest has no subject")
+	ErrDiscoverUnsupported    = errors.New("from target cannot discover referrers")
+	ErrUnsupportedCompression = errors.New("compression unsupported by the layer media type")
+	ErrSizeLimitExceeded      = errors.New("size limit exceeded")
+	ErrCycleDetected          = errors.New("cycle detected")
+)
+
+// Destination verification related errors
+var (
+	ErrRootMismatch   = errors.New("destination ref resolves to another root")
+	ErrSizeMismatch   = orascontent.ErrSizeMismatch
+	ErrDigestMismatch = orascontent.ErrDigestMismatch
 )
 
 // Path validation related errors
@@ -40,3 +54,7 @@ var (
 // ErrStopProcessing is used to stop processing an oras operation.
 // This error only makes sense in sequential pulling operation.
 var ErrStopProcessing = fmt.Errorf("stop processing")
+
+// ErrSkipChildren is returned by a WalkFunc to skip the children of the
+// descriptor visited.
+var ErrSkipChildren = fmt.Errorf("skip children")
@@ -0,0 +1,94 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oras
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	orascontent "oras.land/oras-go/pkg/content"
+)
+
+// ManifestHook rewrites a manifest, or an index, before it is pushed by Copy.
+// It is given the descriptor of the manifest in the source, with its media type
+// converted if requested, and returns the manifest to push, which may be the
+// one given. The store fetches the blobs of the source, such as the config,
+// and pushes new blobs, such as a rewritten config, to the destination. The
+// digests of the rewritten manifests are recomputed, and the parents updated
+// to refer to them.
+type ManifestHook func(ctx context.Context, desc ocispec.Descriptor, manifest []byte, store orascontent.Store) ([]byte, error)
+
+// hookStore fetches from the source and pushes to the destination of a copy
+type hookStore struct {
+	remotes.Fetcher
+	remotes.Pusher
+}
+
+// pushRecorder records the descriptors pushed through a pusher as pushed by a
+// copy, for the destination verification
+type pushRecorder struct {
+	remotes.Pusher
+	lock  *sync.Mutex
+	state *copyState
+}
+
+// Push records the descriptor, then pushes it
+func (p *pushRecorder) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	w, err := p.Pusher.Push(ctx, desc)
+	if err != nil && !errdefs.IsAlreadyExists(err) {
+		return nil, err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.state.pushed = append(p.state.pushed, desc)
+	return w, err
+}
+
+// annotateManifest returns a hook adding the annotations to the manifests,
+// replacing the ones with the same keys
+func annotateManifest(annotations map[string]string) ManifestHook {
+	return func(ctx context.Context, desc ocispec.Descriptor, manifest []byte, store orascontent.Store) ([]byte, error) {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(manifest, &fields); err != nil {
+			return nil, err
+		}
+		current := make(map[string]string, len(annotations))
+		if raw, ok := fields["annotations"]; ok {
+			if err := json.Unmarshal(raw, &current); err != nil {
+				return nil, err
+			}
+		}
+		var changed bool
+		for key, value := range annotations {
+			if existing, ok := current[key]; !ok || existing != value {
+				current[key] = value
+				changed = true
+			}
+		}
+		if !changed {
+			return manifest, nil
+		}
+		var err error
+		if fields["annotations"], err = json.Marshal(current); err != nil {
+			return nil, err
+		}
+		return json.Marshal(fields)
+	}
+}
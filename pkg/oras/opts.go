@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -28,13 +29,15 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/sync/semaphore"
 	orascontent "oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/policy"
+	"oras.land/oras-go/pkg/target"
 )
 
 func copyOptsDefaults() *copyOpts {
 	return &copyOpts{
 		dispatch:         images.Dispatch,
 		filterName:       filterName,
-		cachedMediaTypes: []string{ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex},
+		cachedMediaTypes: orascontent.ManifestMediaTypes(),
 		validateName:     ValidateNameAsPath,
 	}
 }
@@ -50,9 +53,32 @@ type copyOpts struct {
 	filterName                          func(ocispec.Descriptor) bool
 	cachedMediaTypes                    []string
 
-	saveManifest func([]byte)
-	saveLayers   func([]ocispec.Descriptor)
-	validateName func(desc ocispec.Descriptor) error
+	filePatterns []string
+	skipConfig   bool
+	skipManifest bool
+
+	referrers     bool
+	referrerTypes []string
+	// untagged pushes the root without tagging it, as for the referrers
+	untagged bool
+
+	mediaTypeMapping map[string]string
+	layerCompression orascontent.Compression
+	manifestHooks    []ManifestHook
+
+	verifyDestination bool
+
+	maxManifestSize int64
+	maxBlobSize     int64
+	maxArtifactSize int64
+
+	policy       policy.Policy
+	verifySource func(ctx context.Context, from target.Target, ref string, desc ocispec.Descriptor) error
+
+	saveManifest    func([]byte)
+	saveLayers      func([]ocispec.Descriptor)
+	saveDescriptors func(map[digest.Digest]ocispec.Descriptor)
+	validateName    func(desc ocispec.Descriptor) error
 
 	userAgent string
 }
@@ -121,6 +147,62 @@ func filterName(desc ocispec.Descriptor) bool {
 	return true
 }
 
+// filterFile reports whether a blob should be pulled according to the file
+// patterns and the skip config option. isConfig tells if the blob is the config
+// of a manifest seen during the walk.
+func (o *copyOpts) filterFile(desc ocispec.Descriptor, isConfig bool) bool {
+	if isConfig {
+		return !o.skipConfig
+	}
+	if len(o.filePatterns) == 0 {
+		return true
+	}
+	name, ok := orascontent.ResolveName(desc)
+	if !ok || name == "" {
+		return false
+	}
+	for _, pattern := range o.filePatterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSizeLimits checks the size of a descriptor about to be copied against
+// the limits, and adds it to the total size of the copy
+func (o *copyOpts) checkSizeLimits(desc ocispec.Descriptor, state *copyState) error {
+	if isAllowedMediaType(desc.MediaType, o.cachedMediaTypes...) {
+		if o.maxManifestSize > 0 && desc.Size > o.maxManifestSize {
+			return errors.Wrapf(ErrSizeLimitExceeded, "manifest %s of %d bytes exceeds %d bytes", desc.Digest, desc.Size, o.maxManifestSize)
+		}
+	} else if o.maxBlobSize > 0 && desc.Size > o.maxBlobSize {
+		return errors.Wrapf(ErrSizeLimitExceeded, "blob %s of %d bytes exceeds %d bytes", desc.Digest, desc.Size, o.maxBlobSize)
+	}
+	if state.counted[desc.Digest] {
+		return nil
+	}
+	state.counted[desc.Digest] = true
+	state.size += desc.Size
+	if o.maxArtifactSize > 0 && state.size > o.maxArtifactSize {
+		return errors.Wrapf(ErrSizeLimitExceeded, "artifact of %d bytes or more exceeds %d bytes", state.size, o.maxArtifactSize)
+	}
+	return nil
+}
+
+// storeCachedMediaTypes returns the media types cached by the store of a copy:
+// the cached media types, and the ones the mapping converts them to, so that
+// the rewritten manifests are cached as well
+func (o *copyOpts) storeCachedMediaTypes() []string {
+	mediaTypes := append([]string{}, o.cachedMediaTypes...)
+	for from, to := range o.mediaTypeMapping {
+		if isAllowedMediaType(from, o.cachedMediaTypes...) {
+			mediaTypes = append(mediaTypes, to)
+		}
+	}
+	return mediaTypes
+}
+
 // WithAdditionalCachedMediaTypes adds media types normally cached in memory when pulling.
 // This does not replace the default media types, but appends to them
 func WithAdditionalCachedMediaTypes(cachedMediaTypes ...string) CopyOpt {
@@ -189,6 +271,40 @@ func WithPullEmptyNameAllowed() CopyOpt {
 	}
 }
 
+// WithPullFilePatterns pulls only the layers whose name, as resolved by
+// orascontent.ResolveName, matches at least one of the glob patterns. Patterns
+// use the syntax of path.Match, e.g. "docs/*.md". Layers without a name are
+// skipped. The manifest and the config are still copied, unless
+// WithPullSkipManifest or WithPullSkipConfig is passed as well.
+func WithPullFilePatterns(patterns ...string) CopyOpt {
+	return func(o *copyOpts) error {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return errors.Wrap(err, pattern)
+			}
+		}
+		o.filePatterns = append(o.filePatterns, patterns...)
+		return nil
+	}
+}
+
+// WithPullSkipConfig does not copy the config of the manifests.
+func WithPullSkipConfig() CopyOpt {
+	return func(o *copyOpts) error {
+		o.skipConfig = true
+		return nil
+	}
+}
+
+// WithPullSkipManifest does not push the manifests to the destination. They are
+// still fetched from the source to find the blobs to copy.
+func WithPullSkipManifest() CopyOpt {
+	return func(o *copyOpts) error {
+		o.skipManifest = true
+		return nil
+	}
+}
+
 // WithPullStatusTrack report results to stdout
 func WithPullStatusTrack(writer io.Writer) CopyOpt {
 	return WithPullCallbackHandler(pullStatusTrack(writer))
@@ -252,3 +368,180 @@ func WithRootManifest(save func(b []byte)) CopyOpt {
 		return nil
 	}
 }
+
+// WithReferrers copies the referrers of every manifest copied, such as
+// signatures and SBOMs, and recursively their own referrers. If artifact types
+// are given, only the referrers of those types are copied. The referrers are
+// pushed to the repository of the destination reference without tags, and the
+// referrers index of the destination is updated when it keeps one. The source
+// target must implement target.Discoverer.
+func WithReferrers(artifactTypes ...string) CopyOpt {
+	return func(o *copyOpts) error {
+		o.referrers = true
+		o.referrerTypes = append(o.referrerTypes, artifactTypes...)
+		return nil
+	}
+}
+
+// WithConvertToOCI converts the Docker schema2 manifests, manifest lists and
+// the media types they refer to into their OCI equivalents while copying. The
+// blobs are copied as is. The converted manifests have new digests, so the root
+// returned by Copy, and tagged at the destination, is the converted one. Use
+// WithDescriptorMapping to get the digests of the converted manifests.
+func WithConvertToOCI() CopyOpt {
+	return WithMediaTypeMapping(dockerToOCI)
+}
+
+// WithConvertToDocker converts the OCI manifests, indexes and the media types
+// they refer to into their Docker schema2 equivalents while copying, as
+// WithConvertToOCI does the other way round. Media types without a Docker
+// equivalent, such as artifact manifests, are kept.
+func WithConvertToDocker() CopyOpt {
+	return WithMediaTypeMapping(ociToDocker)
+}
+
+// WithMediaTypeMapping rewrites the media types of the manifests, and of the
+// descriptors they hold, from the keys to the values of the mapping while
+// copying. The digests and sizes of the rewritten manifests are recomputed, and
+// the parents updated to refer to them. Mappings of several options are merged,
+// the later ones taking precedence.
+func WithMediaTypeMapping(mapping map[string]string) CopyOpt {
+	return func(o *copyOpts) error {
+		if o.mediaTypeMapping == nil {
+			o.mediaTypeMapping = make(map[string]string, len(mapping))
+		}
+		for from, to := range mapping {
+			o.mediaTypeMapping[from] = to
+		}
+		return nil
+	}
+}
+
+// WithLayerCompression recompresses the tar layers of the images with the
+// compression while copying, e.g. from gzip to zstd. The layers already using
+// the compression, as told by their media type, and the blobs of other media
+// types, such as the files of artifacts, are copied as is. The manifests are
+// rewritten to refer to the recompressed layers. Docker layers do not support
+// zstd, unless converted with WithConvertToOCI.
+func WithLayerCompression(c orascontent.Compression) CopyOpt {
+	return func(o *copyOpts) error {
+		switch c {
+		case orascontent.CompressionNone, orascontent.CompressionGzip, orascontent.CompressionZstd:
+		default:
+			return errors.Wrap(orascontent.ErrUnsupported, string(c))
+		}
+		o.layerCompression = c
+		return nil
+	}
+}
+
+// WithManifestHook runs the hooks, in order, on every manifest and index copied
+// before it is pushed, after the other rewrites such as WithConvertToOCI. The
+// returned root, and the tag at the destination, are the rewritten ones.
+func WithManifestHook(hooks ...ManifestHook) CopyOpt {
+	return func(o *copyOpts) error {
+		for _, hook := range hooks {
+			if hook == nil {
+				return errors.New("manifest hook must be non-nil")
+			}
+		}
+		o.manifestHooks = append(o.manifestHooks, hooks...)
+		return nil
+	}
+}
+
+// WithManifestAnnotations adds the annotations to every manifest and index
+// copied, replacing the ones with the same keys, e.g. to stamp the source and
+// time of a promotion.
+func WithManifestAnnotations(annotations map[string]string) CopyOpt {
+	return WithManifestHook(annotateManifest(annotations))
+}
+
+// WithDestinationVerification verifies the destination once copied: the
+// destination reference, unless it names a digest, must resolve to the root,
+// and every manifest and blob pushed, referrers included, is fetched back and
+// checked against its size and digest. The discrepancies are returned as a
+// *VerificationError.
+func WithDestinationVerification() CopyOpt {
+	return func(o *copyOpts) error {
+		o.verifyDestination = true
+		return nil
+	}
+}
+
+// WithMaxManifestSize limits the size of each manifest and index copied, which
+// are held in memory. The copy fails with ErrSizeLimitExceeded before fetching a
+// larger manifest, or while fetching one larger than its descriptor tells.
+func WithMaxManifestSize(size int64) CopyOpt {
+	return func(o *copyOpts) error {
+		if size <= 0 {
+			return errors.New("max manifest size must be greater than 0")
+		}
+		o.maxManifestSize = size
+		return nil
+	}
+}
+
+// WithMaxBlobSize limits the size of each blob copied, such as the config and
+// the layers, as WithMaxManifestSize does for manifests.
+func WithMaxBlobSize(size int64) CopyOpt {
+	return func(o *copyOpts) error {
+		if size <= 0 {
+			return errors.New("max blob size must be greater than 0")
+		}
+		o.maxBlobSize = size
+		return nil
+	}
+}
+
+// WithMaxArtifactSize limits the total size of the distinct manifests and
+// blobs copied, referrers included. The blobs filtered out are not counted.
+func WithMaxArtifactSize(size int64) CopyOpt {
+	return func(o *copyOpts) error {
+		if size <= 0 {
+			return errors.New("max artifact size must be greater than 0")
+		}
+		o.maxArtifactSize = size
+		return nil
+	}
+}
+
+// WithPolicy evaluates the policy against the source reference, each
+// descriptor before it is transferred, and each manifest before its children
+// are. The copy stops at the first error, such as a *policy.Violation.
+func WithPolicy(p policy.Policy) CopyOpt {
+	return func(o *copyOpts) error {
+		if p == nil {
+			return errors.New("policy must be non-nil")
+		}
+		o.policy = p
+		return nil
+	}
+}
+
+// WithSourceVerification calls verify with the source target, reference and
+// resolved root descriptor before any content is copied. The copy does not
+// proceed if verify returns an error, such as when the root is not signed by a
+// trusted key. If the passed parameter is nil, returns an error.
+func WithSourceVerification(verify func(ctx context.Context, from target.Target, ref string, desc ocispec.Descriptor) error) CopyOpt {
+	return func(o *copyOpts) error {
+		if verify == nil {
+			return errors.New("source verification func must be non-nil")
+		}
+		o.verifySource = verify
+		return nil
+	}
+}
+
+// WithDescriptorMapping passes the descriptors of the manifests and layers
+// rewritten while copying, keyed by their original digest, to the provided func. If the passed
+// parameter is nil, returns an error.
+func WithDescriptorMapping(save func(map[digest.Digest]ocispec.Descriptor)) CopyOpt {
+	return func(o *copyOpts) error {
+		if save == nil {
+			return errors.New("descriptor mapping save func must be non-nil")
+		}
+		o.saveDescriptors = save
+		return nil
+	}
+}
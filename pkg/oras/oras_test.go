@@ -21,9 +21,11 @@ import (
 	"compress/gzip"
 	"context"
 	_ "crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
@@ -86,6 +88,21 @@ func (suite *ORASTestSuite) SetupSuite() {
 	suite.Nil(err, "no error finding free port for test registry")
 
 	go dockerRegistry.ListenAndServe()
+	suite.waitForRegistry()
+}
+
+// waitForRegistry waits for the test registry to accept connections, as it is
+// started in the background
+func (suite *ORASTestSuite) waitForRegistry() {
+	for i := 0; i < 50; i++ {
+		conn, err := net.Dial("tcp", suite.DockerRegistryHost)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	suite.FailNow("test registry not accepting connections")
 }
 
 // Push files to docker registry
@@ -507,6 +524,86 @@ func (suite *ORASTestSuite) Test_4_GHSA_g5v4_5x39_vwhx() {
 	}
 }
 
+// Push a referrer to the docker registry and discover it
+func (suite *ORASTestSuite) Test_5_PushReferrer() {
+	ctx := newContext()
+	repository := fmt.Sprintf("%s/referrers", suite.DockerRegistryHost)
+	ref := repository + ":image"
+
+	// push the subject
+	memStore := orascontent.NewMemory()
+	config, configDesc, err := orascontent.GenerateConfig(nil)
+	suite.Nil(err, "no error generating config")
+	memStore.Set(configDesc, config)
+	manifest, manifestDesc, err := orascontent.GenerateManifest(&configDesc, nil)
+	suite.Nil(err, "no error generating subject manifest")
+	err = memStore.StoreManifest(ref, manifestDesc, manifest)
+	suite.Nil(err, "no error storing subject manifest")
+	subject, err := Copy(ctx, memStore, ref, newResolver(), "")
+	suite.Nil(err, "no error pushing subject")
+
+	// a manifest without subject is not a referrer
+	_, err = PushReferrer(ctx, memStore, ref, newResolver(), repository)
+	suite.Equal(ErrNoSubject, err, "error pushing a manifest without subject")
+
+	// push a signature referring to the subject
+	emptyConfig, emptyConfigDesc, err := orascontent.GenerateEmptyConfig()
+	suite.Nil(err, "no error generating empty config")
+	memStore.Set(emptyConfigDesc, emptyConfig)
+	signatureDesc, err := memStore.Add("signature", "application/vnd.example.signature.layer", []byte("signature"))
+	suite.Nil(err, "no error adding signature")
+	referrer, referrerDesc, err := orascontent.GenerateManifestWithOpts(nil, nil, []ocispec.Descriptor{signatureDesc},
+		orascontent.WithSubject(subject),
+		orascontent.WithArtifactType("application/vnd.example.signature"),
+	)
+	suite.Nil(err, "no error generating referrer manifest")
+	err = memStore.StoreManifest("signature", referrerDesc, referrer)
+	suite.Nil(err, "no error storing referrer manifest")
+
+	registry := newResolver()
+	desc, err := PushReferrer(ctx, memStore, "signature", registry, repository)
+	suite.Nil(err, "no error pushing referrer")
+	suite.Equal(referrerDesc.Digest, desc.Digest, "referrer pushed")
+	// pushing again does not duplicate the referrer
+	_, err = PushReferrer(ctx, memStore, "signature", registry, repository)
+	suite.Nil(err, "no error pushing referrer again")
+	// the referrer manifest is not read past the size limit
+	_, err = PushReferrer(ctx, memStore, "signature", registry, repository, WithMaxManifestSize(referrerDesc.Size-1))
+	suite.True(errors.Is(err, ErrSizeLimitExceeded), "error pushing a referrer manifest over the size limit")
+	// the referrer manifest read is verified
+	_, err = PushReferrer(ctx, &faultyTarget{Memory: memStore, corrupt: referrerDesc.Digest}, "signature", registry, repository)
+	suite.True(errors.Is(err, ErrDigestMismatch), "error pushing a corrupt referrer manifest: %v", err)
+
+	referrers, err := registry.(target.Discoverer).Discover(ctx, repository, subject, "")
+	suite.Nil(err, "no error discovering referrers")
+	suite.Len(referrers, 1, "one referrer discovered")
+	if len(referrers) == 1 {
+		suite.Equal(referrerDesc.Digest, referrers[0].Digest, "referrer discovered")
+		suite.Equal("application/vnd.example.signature", referrers[0].ArtifactType, "artifact type discovered")
+	}
+
+	// copying the image to another repository brings the referrer along
+	copied := fmt.Sprintf("%s/referrers-copy:image", suite.DockerRegistryHost)
+	destination := newResolver()
+	_, err = Copy(ctx, registry, ref, destination, copied, WithReferrers(), WithDestinationVerification())
+	suite.Nil(err, "no error copying with referrers")
+	referrers, err = destination.(target.Discoverer).Discover(ctx, copied, subject, "")
+	suite.Nil(err, "no error discovering copied referrers")
+	suite.Len(referrers, 1, "one referrer copied")
+
+	// the referrer is pushed untagged to stores without repositories
+	to := orascontent.NewMemory()
+	_, err = Copy(ctx, memStore, ref, to, "")
+	suite.Nil(err, "no error copying subject")
+	_, err = PushReferrer(ctx, memStore, "signature", to, "")
+	suite.Nil(err, "no error pushing referrer to memory")
+	_, _, err = to.Resolve(ctx, "")
+	suite.NotNil(err, "referrer not tagged")
+	referrers, err = to.Discover(ctx, "", subject, "application/vnd.example.signature")
+	suite.Nil(err, "no error discovering referrers in memory")
+	suite.Len(referrers, 1, "one referrer discovered in memory")
+}
+
 func TestORASTestSuite(t *testing.T) {
 	suite.Run(t, new(ORASTestSuite))
 }
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"io/ioutil"
 
 	"github.com/containerd/containerd/content"
 	"github.com/containerd/containerd/remotes"
@@ -61,19 +62,53 @@ func (f *fetcherReaderAt) Size() int64 {
 }
 
 func (f *fetcherReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if f.desc.Size > 0 && off >= f.desc.Size {
+		return 0, io.EOF
+	}
+	if err := f.seek(off); err != nil {
+		return 0, err
+	}
+
+	n, err = io.ReadFull(f.rc, p)
+	f.offset += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// seek positions the underlying reader at off. Readers implementing io.Seeker,
+// such as those of registry fetchers which issue HTTP Range requests, seek
+// directly. Other readers are read sequentially, skipping forward, and are
+// fetched again from the start to go backward.
+func (f *fetcherReaderAt) seek(off int64) error {
+	if f.rc != nil && f.offset == off {
+		return nil
+	}
+	if f.rc != nil && off < f.offset {
+		if _, ok := f.rc.(io.Seeker); !ok {
+			f.rc.Close()
+			f.rc = nil
+		}
+	}
 	// if we do not have a readcloser, get it
-	if f.rc == nil || f.offset != off {
+	if f.rc == nil {
 		rc, err := f.fetcher.Fetch(f.ctx, f.desc)
 		if err != nil {
-			return 0, err
+			return err
 		}
 		f.rc = rc
+		f.offset = 0
 	}
 
-	n, err = f.rc.Read(p)
-	if err != nil {
-		return n, err
+	if seeker, ok := f.rc.(io.Seeker); ok {
+		if _, err := seeker.Seek(off, io.SeekStart); err != nil {
+			return err
+		}
+		f.offset = off
+		return nil
 	}
-	f.offset += int64(n)
-	return n, err
+	n, err := io.CopyN(ioutil.Discard, f.rc, off-f.offset)
+	f.offset += n
+	return err
 }
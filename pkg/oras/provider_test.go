@@ -0,0 +1,145 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oras
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/suite"
+	orascontent "oras.land/oras-go/pkg/content"
+)
+
+type ProviderTestSuite struct {
+	suite.Suite
+	content []byte
+	desc    ocispec.Descriptor
+}
+
+func (suite *ProviderTestSuite) SetupSuite() {
+	suite.content = []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	suite.desc = ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.FromBytes(suite.content),
+		Size:      int64(len(suite.content)),
+	}
+}
+
+// blobServer serves the test blob like a registry, honouring Range requests if
+// supportRange is set. It records the Range header of each blob request.
+func (suite *ProviderTestSuite) blobServer(supportRange bool) (*httptest.Server, func() []string) {
+	var (
+		lock   sync.Mutex
+		ranges []string
+	)
+	blobPath := fmt.Sprintf("/v2/test/blobs/%s", suite.desc.Digest)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != blobPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rangeHeader := r.Header.Get("Range")
+		lock.Lock()
+		ranges = append(ranges, rangeHeader)
+		lock.Unlock()
+
+		var start int
+		if supportRange && rangeHeader != "" {
+			if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-", &start); err != nil {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(suite.content)-1, len(suite.content)))
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		w.Write(suite.content[start:])
+	}))
+	return server, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), ranges...)
+	}
+}
+
+func (suite *ProviderTestSuite) readerAt(server *httptest.Server) io.ReaderAt {
+	u, err := url.Parse(server.URL)
+	suite.Nil(err, "no error parsing server url")
+	reg, err := orascontent.NewRegistry(orascontent.RegistryOptions{PlainHTTP: true})
+	suite.Nil(err, "no error creating registry")
+	fetcher, err := reg.Fetcher(context.Background(), fmt.Sprintf("%s/test:latest", u.Host))
+	suite.Nil(err, "no error getting fetcher")
+	ra, err := (&ProviderWrapper{Fetcher: fetcher}).ReaderAt(context.Background(), suite.desc)
+	suite.Nil(err, "no error getting ReaderAt")
+	return ra
+}
+
+// checkReads reads at various offsets, going forward, backward and sequentially
+func (suite *ProviderTestSuite) checkReads(ra io.ReaderAt) {
+	for _, off := range []int64{10, 20, 2, 6, 30} {
+		p := make([]byte, 4)
+		n, err := ra.ReadAt(p, off)
+		suite.Nil(err, "no error reading at %d", off)
+		suite.Equal(string(suite.content[off:off+4]), string(p[:n]), "content matches at %d", off)
+	}
+	p := make([]byte, 10)
+	n, err := ra.ReadAt(p, 32)
+	suite.Equal(io.EOF, err, "EOF reading past the end")
+	suite.Equal("wxyz", string(p[:n]), "content matches at the end")
+}
+
+func (suite *ProviderTestSuite) Test_0_RangeRequests() {
+	server, ranges := suite.blobServer(true)
+	defer server.Close()
+
+	suite.checkReads(suite.readerAt(server))
+	suite.Equal([]string{"bytes=10-", "bytes=20-", "bytes=2-", "bytes=30-", "bytes=32-"}, ranges(),
+		"each non-sequential read issues a single Range request")
+}
+
+func (suite *ProviderTestSuite) Test_1_NoRangeSupport() {
+	server, ranges := suite.blobServer(false)
+	defer server.Close()
+
+	suite.checkReads(suite.readerAt(server))
+	suite.Len(ranges(), 5, "content still read correctly without Range support")
+}
+
+func (suite *ProviderTestSuite) Test_2_SequentialFallback() {
+	store := orascontent.NewMemory()
+	store.Set(suite.desc, suite.content)
+	ra, err := (&ProviderWrapper{Fetcher: store}).ReaderAt(context.Background(), suite.desc)
+	suite.Nil(err, "no error getting ReaderAt")
+	suite.checkReads(ra)
+
+	// sequential reads with the wrapper
+	b := new(strings.Builder)
+	_, err = io.Copy(b, orascontent.NewReaderAtWrapper(ra))
+	suite.Nil(err, "no error reading sequentially")
+	suite.Equal(string(suite.content), b.String(), "content matches when read sequentially")
+}
+
+func TestProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(ProviderTestSuite))
+}
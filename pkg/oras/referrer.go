@@ -0,0 +1,115 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oras
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+
+	"oras.land/oras-go/pkg/artifact"
+	orascontent "oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/target"
+)
+
+// maxReferrerManifestSize is the size over which the referrer manifests are not
+// fetched, unless WithMaxManifestSize sets another limit
+const maxReferrerManifestSize = 4 * 1024 * 1024
+
+// PushReferrer copies the referrer manifest fromRef, which has a subject, from
+// one target.Target to a repository of another target.Target, without tagging
+// it. The repository is a reference without tag nor digest, and may be empty
+// for targets without repositories such as the Memory and OCI stores. If the
+// destination keeps an index of the referrers of each subject, the referrer is
+// added to it. Returns the descriptor of the referrer.
+func PushReferrer(ctx context.Context, from target.Target, fromRef string, to target.Target, repository string, opts ...CopyOpt) (ocispec.Descriptor, error) {
+	if from == nil {
+		return ocispec.Descriptor{}, ErrFromTargetUndefined
+	}
+	if to == nil {
+		return ocispec.Descriptor{}, ErrToTargetUndefined
+	}
+	opt := copyOptsDefaults()
+	for _, o := range opts {
+		if err := o(opt); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+
+	// check the subject before pushing anything
+	_, root, err := from.Resolve(ctx, fromRef)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	fetcher, err := from.Fetcher(ctx, fromRef)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	maxSize := opt.maxManifestSize
+	if maxSize <= 0 {
+		maxSize = maxReferrerManifestSize
+	}
+	if root.Size > maxSize {
+		return ocispec.Descriptor{}, errors.Wrapf(ErrSizeLimitExceeded, "manifest %s of %d bytes exceeds %d bytes", root.Digest, root.Size, maxSize)
+	}
+	// the content read is limited to the size of the root, and verified
+	rc, err := orascontent.NewVerifyingFetcher(fetcher).Fetch(ctx, root)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer rc.Close()
+	p, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	var manifest artifact.ImageManifest
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if manifest.Subject == nil {
+		return ocispec.Descriptor{}, ErrNoSubject
+	}
+
+	opt.untagged = true
+	desc, err := copyRoot(ctx, from, fromRef, to, repository, opt)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	indexer, ok := to.(target.ReferrerIndexer)
+	if !ok {
+		return desc, nil
+	}
+	artifactType := manifest.ArtifactType
+	if artifactType == "" {
+		artifactType = manifest.Config.MediaType
+	}
+	referrer := artifact.Descriptor{
+		Descriptor: ocispec.Descriptor{
+			MediaType:   desc.MediaType,
+			Digest:      desc.Digest,
+			Size:        desc.Size,
+			Annotations: manifest.Annotations,
+		},
+		ArtifactType: artifactType,
+	}
+	if err := indexer.IndexReferrer(ctx, repository, *manifest.Subject, referrer); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return desc, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oras
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	orascontent "oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/target"
+)
+
+// Discrepancy is a descriptor the destination of a copy does not hold as sent
+type Discrepancy struct {
+	Descriptor ocispec.Descriptor
+	Err        error
+}
+
+// VerificationError lists the discrepancies found when verifying the
+// destination of a copy
+type VerificationError struct {
+	Ref           string
+	Discrepancies []Discrepancy
+}
+
+func (e *VerificationError) Error() string {
+	messages := make([]string, 0, len(e.Discrepancies))
+	for _, d := range e.Discrepancies {
+		messages = append(messages, fmt.Sprintf("%s: %v", d.Descriptor.Digest, d.Err))
+	}
+	return fmt.Sprintf("verification of %s failed: %s", e.Ref, strings.Join(messages, "; "))
+}
+
+// verifyDestination checks that the ref of the destination resolves to the
+// root, if asked and the ref is a tag, then fetches every descriptor pushed
+// and checks its size and digest
+func verifyDestination(ctx context.Context, to target.Target, ref string, root ocispec.Descriptor, pushed []ocispec.Descriptor, checkRoot bool) error {
+	verr := &VerificationError{Ref: ref}
+	if checkRoot && tagsRoot(ref) {
+		_, desc, err := to.Resolve(ctx, ref)
+		switch {
+		case err != nil:
+			verr.Discrepancies = append(verr.Discrepancies, Discrepancy{Descriptor: root, Err: err})
+		case desc.Digest != root.Digest:
+			verr.Discrepancies = append(verr.Discrepancies, Discrepancy{
+				Descriptor: root,
+				Err:        errors.Wrapf(ErrRootMismatch, "resolved %s", desc.Digest),
+			})
+		}
+	}
+
+	// stores such as Memory and OCI fetch by descriptor whether the ref is
+	// tagged or not, while their fetchers need a known ref
+	fetcher, ok := to.(remotes.Fetcher)
+	if !ok {
+		var err error
+		if fetcher, err = to.Fetcher(ctx, ref); err != nil {
+			return err
+		}
+	}
+	verified := make(map[digest.Digest]bool, len(pushed))
+	for _, desc := range pushed {
+		if verified[desc.Digest] {
+			continue
+		}
+		verified[desc.Digest] = true
+		if err := verifyDescriptor(ctx, fetcher, desc); err != nil {
+			verr.Discrepancies = append(verr.Discrepancies, Discrepancy{Descriptor: desc, Err: err})
+		}
+	}
+	if len(verr.Discrepancies) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// verifyDescriptor fetches the content of the descriptor, and checks its size
+// and digest
+func verifyDescriptor(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) error {
+	rc, err := orascontent.NewVerifyingFetcher(fetcher).Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(ioutil.Discard, rc)
+	return err
+}
+
+// tagsRoot reports whether the root is pushed with the reference as a tag:
+// any reference but an empty one or one naming a digest, the tags of the
+// Memory and OCI stores included
+func tagsRoot(ref string) bool {
+	return ref != "" && !strings.Contains(ref, "@")
+}
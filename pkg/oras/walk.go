@@ -0,0 +1,401 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oras
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	"oras.land/oras-go/pkg/artifact"
+	orascontent "oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/target"
+)
+
+// Node is a descriptor visited by Walk
+type Node struct {
+	Descriptor ocispec.Descriptor
+
+	// Parents are the descriptors from the root down to the parent of the
+	// descriptor, empty for the root
+	Parents []ocispec.Descriptor
+
+	// Depth is the number of parents, 0 for the root
+	Depth int
+
+	// Content is the content of a manifest or an index, nil for the blobs
+	Content []byte
+
+	// Manifest is the parsed content of a manifest or an index: an
+	// *artifact.ImageManifest for the OCI and Docker image manifests, an
+	// *ocispec.Index for the OCI indexes and Docker manifest lists, and an
+	// *artifact.Manifest for the artifact manifests. It is nil for the blobs.
+	Manifest interface{}
+
+	// Children are the descriptors referenced by the manifest or the index
+	Children []ocispec.Descriptor
+}
+
+// WalkFunc is called by Walk for each descriptor visited. Returning
+// ErrSkipChildren from a pre-order call skips the children of the descriptor,
+// while any other error stops the walk and is returned by Walk.
+type WalkFunc func(ctx context.Context, node Node) error
+
+// Walk visits every descriptor reachable from the root ref resolves to in the
+// target, fetching and parsing the manifests and indexes on the way to find
+// their children. The blobs are not fetched. Each digest is visited once, even
+// if referenced several times, and a manifest referencing one of its parents is
+// reported with ErrCycleDetected.
+//
+// By default, the walk is depth-first, pre-order, without depth limit, and
+// sequential. With WithWalkConcurrency, fn may be called concurrently.
+func Walk(ctx context.Context, from target.Target, ref string, fn WalkFunc, opts ...WalkOpt) error {
+	if from == nil {
+		return ErrFromTargetUndefined
+	}
+	if fn == nil {
+		return errors.New("walk func must be non-nil")
+	}
+	opt := walkOptsDefaults()
+	for _, o := range opts {
+		if err := o(opt); err != nil {
+			return err
+		}
+	}
+	_, root, err := from.Resolve(ctx, ref)
+	if err != nil {
+		return err
+	}
+	fetcher, err := from.Fetcher(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return walk(ctx, fetcher, root, fn, opt)
+}
+
+// walk visits the graph of root, fetching from the fetcher
+func walk(ctx context.Context, fetcher remotes.Fetcher, root ocispec.Descriptor, fn WalkFunc, opt *walkOpts) error {
+	w := &walker{
+		fetcher: fetcher,
+		fn:      fn,
+		opt:     opt,
+		visited: make(map[digest.Digest]bool),
+	}
+	if opt.concurrency > 1 {
+		w.sem = semaphore.NewWeighted(int64(opt.concurrency))
+		w.workers = semaphore.NewWeighted(int64(opt.concurrency))
+	}
+	if opt.breadthFirst {
+		return w.bfs(ctx, root)
+	}
+	return w.dfs(ctx, root, nil)
+}
+
+// walker holds the state of a walk
+type walker struct {
+	fetcher remotes.Fetcher
+	fn      WalkFunc
+	opt     *walkOpts
+
+	// sem limits the concurrent fetches, and workers the goroutines visiting
+	// siblings, both to the concurrency of the walk
+	sem     *semaphore.Weighted
+	workers *semaphore.Weighted
+
+	lock    sync.Mutex
+	visited map[digest.Digest]bool
+}
+
+// dfs visits the graph of desc depth-first
+func (w *walker) dfs(ctx context.Context, desc ocispec.Descriptor, parents []ocispec.Descriptor) error {
+	if err := checkCycle(desc, parents); err != nil {
+		return err
+	}
+	if !w.visit(desc) {
+		return nil
+	}
+	node, err := w.load(ctx, desc, parents)
+	if err != nil {
+		return err
+	}
+
+	skip := false
+	if !w.opt.postOrder {
+		if err := w.fn(ctx, node); err == ErrSkipChildren {
+			skip = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if !skip && w.descend(node) {
+		childParents := append(append([]ocispec.Descriptor{}, parents...), desc)
+		if err := w.each(ctx, len(node.Children), func(ctx context.Context, i int) error {
+			return w.dfs(ctx, node.Children[i], childParents)
+		}); err != nil {
+			return err
+		}
+	}
+	if w.opt.postOrder {
+		if err := w.fn(ctx, node); err != nil && err != ErrSkipChildren {
+			return err
+		}
+	}
+	return nil
+}
+
+// bfs visits the graph of root breadth-first, level by level. In post-order,
+// the levels are visited from the deepest one up to the root.
+func (w *walker) bfs(ctx context.Context, root ocispec.Descriptor) error {
+	var levels [][]Node
+	w.visit(root)
+	queue := []Node{{Descriptor: root}}
+	for len(queue) > 0 {
+		level := make([]Node, len(queue))
+		if err := w.each(ctx, len(queue), func(ctx context.Context, i int) error {
+			node, err := w.load(ctx, queue[i].Descriptor, queue[i].Parents)
+			level[i] = node
+			return err
+		}); err != nil {
+			return err
+		}
+
+		queue = nil
+		for _, node := range level {
+			if !w.opt.postOrder {
+				if err := w.fn(ctx, node); err == ErrSkipChildren {
+					continue
+				} else if err != nil {
+					return err
+				}
+			}
+			if !w.descend(node) {
+				continue
+			}
+			parents := append(append([]ocispec.Descriptor{}, node.Parents...), node.Descriptor)
+			for _, child := range node.Children {
+				if err := checkCycle(child, parents); err != nil {
+					return err
+				}
+				if w.visit(child) {
+					queue = append(queue, Node{Descriptor: child, Parents: parents})
+				}
+			}
+		}
+		if w.opt.postOrder {
+			levels = append(levels, level)
+		}
+	}
+	for i := len(levels) - 1; i >= 0; i-- {
+		for _, node := range levels[i] {
+			if err := w.fn(ctx, node); err != nil && err != ErrSkipChildren {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// visit marks the descriptor visited, and returns whether it is to be visited,
+// that is if it was not visited already and has an allowed media type
+func (w *walker) visit(desc ocispec.Descriptor) bool {
+	if !orascontent.IsManifestMediaType(desc.MediaType) && !isAllowedMediaType(desc.MediaType, w.opt.allowedMediaTypes...) {
+		return false
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.visited[desc.Digest] {
+		return false
+	}
+	w.visited[desc.Digest] = true
+	return true
+}
+
+// descend returns whether the children of the node are to be visited
+func (w *walker) descend(node Node) bool {
+	return w.opt.maxDepth < 0 || node.Depth < w.opt.maxDepth
+}
+
+// load fetches and parses the content of the manifests and indexes
+func (w *walker) load(ctx context.Context, desc ocispec.Descriptor, parents []ocispec.Descriptor) (Node, error) {
+	node := Node{
+		Descriptor: desc,
+		Parents:    parents,
+		Depth:      len(parents),
+	}
+	if !orascontent.IsManifestMediaType(desc.MediaType) {
+		return node, nil
+	}
+	if w.sem != nil {
+		if err := w.sem.Acquire(ctx, 1); err != nil {
+			return Node{}, err
+		}
+		defer w.sem.Release(1)
+	}
+	p, err := content.ReadBlob(ctx, &ProviderWrapper{Fetcher: w.fetcher}, desc)
+	if err != nil {
+		return Node{}, err
+	}
+	node.Content = p
+
+	switch desc.MediaType {
+	case ocispec.MediaTypeImageManifest, images.MediaTypeDockerSchema2Manifest:
+		var manifest artifact.ImageManifest
+		if err := json.Unmarshal(p, &manifest); err != nil {
+			return Node{}, errors.Wrap(err, desc.Digest.String())
+		}
+		node.Manifest = &manifest
+		node.Children = append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...)
+	case ocispec.MediaTypeImageIndex, images.MediaTypeDockerSchema2ManifestList:
+		var index ocispec.Index
+		if err := json.Unmarshal(p, &index); err != nil {
+			return Node{}, errors.Wrap(err, desc.Digest.String())
+		}
+		node.Manifest = &index
+		node.Children = index.Manifests
+	case artifact.MediaTypeArtifactManifest:
+		var manifest artifact.Manifest
+		if err := json.Unmarshal(p, &manifest); err != nil {
+			return Node{}, errors.Wrap(err, desc.Digest.String())
+		}
+		node.Manifest = &manifest
+		node.Children = manifest.Blobs
+	}
+	return node, nil
+}
+
+// each calls f for each index up to n, concurrently if the walk is. At most
+// concurrency goroutines are started for the whole walk: when none is left, f
+// is called in the calling goroutine, so that the recursive calls cannot wait
+// on each other.
+func (w *walker) each(ctx context.Context, n int, f func(context.Context, int) error) error {
+	if w.sem == nil {
+		for i := 0; i < n; i++ {
+			if err := f(ctx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	eg, egCtx := errgroup.WithContext(ctx)
+	var err error
+	for i := 0; i < n && err == nil; i++ {
+		i := i
+		if !w.workers.TryAcquire(1) {
+			if err = f(egCtx, i); err != nil {
+				cancel()
+			}
+			continue
+		}
+		eg.Go(func() error {
+			defer w.workers.Release(1)
+			return f(egCtx, i)
+		})
+	}
+	if egErr := eg.Wait(); err == nil {
+		err = egErr
+	}
+	return err
+}
+
+// checkCycle returns ErrCycleDetected if the descriptor is one of its parents
+func checkCycle(desc ocispec.Descriptor, parents []ocispec.Descriptor) error {
+	for _, parent := range parents {
+		if parent.Digest == desc.Digest {
+			return errors.Wrapf(ErrCycleDetected, "%s references its parent %s", parents[len(parents)-1].Digest, desc.Digest)
+		}
+	}
+	return nil
+}
+
+type WalkOpt func(o *walkOpts) error
+
+type walkOpts struct {
+	postOrder         bool
+	breadthFirst      bool
+	maxDepth          int
+	concurrency       int
+	allowedMediaTypes []string
+}
+
+func walkOptsDefaults() *walkOpts {
+	return &walkOpts{
+		maxDepth: -1,
+	}
+}
+
+// WithWalkPostOrder visits the children of each descriptor before the
+// descriptor itself, instead of after
+func WithWalkPostOrder() WalkOpt {
+	return func(o *walkOpts) error {
+		o.postOrder = true
+		return nil
+	}
+}
+
+// WithWalkBFS walks breadth-first, visiting all the descriptors of a depth
+// before the ones of the next depth, instead of depth-first. In post-order, the
+// deepest descriptors are visited first.
+func WithWalkBFS() WalkOpt {
+	return func(o *walkOpts) error {
+		o.breadthFirst = true
+		return nil
+	}
+}
+
+// WithWalkMaxDepth does not visit the descriptors deeper than depth, the root
+// being at depth 0
+func WithWalkMaxDepth(depth int) WalkOpt {
+	return func(o *walkOpts) error {
+		if depth < 0 {
+			return errors.New("max depth must not be negative")
+		}
+		o.maxDepth = depth
+		return nil
+	}
+}
+
+// WithWalkConcurrency fetches up to n manifests concurrently, visiting the
+// siblings concurrently: the walk func must then be safe for concurrent use.
+// The parents are still visited before their children in pre-order, and after
+// them in post-order.
+func WithWalkConcurrency(n int) WalkOpt {
+	return func(o *walkOpts) error {
+		if n <= 0 {
+			return errors.New("concurrency must be greater than 0")
+		}
+		o.concurrency = n
+		return nil
+	}
+}
+
+// WithWalkMediaTypes only visits the blobs with one of the media types. The
+// manifests and indexes are always visited.
+func WithWalkMediaTypes(mediaTypes ...string) WalkOpt {
+	return func(o *walkOpts) error {
+		o.allowedMediaTypes = append(o.allowedMediaTypes, mediaTypes...)
+		return nil
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oras
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/suite"
+	"oras.land/oras-go/pkg/artifact"
+	orascontent "oras.land/oras-go/pkg/content"
+)
+
+type WalkTestSuite struct {
+	suite.Suite
+	ref   string
+	store *orascontent.Memory
+	files map[string]string
+}
+
+func (suite *WalkTestSuite) SetupTest() {
+	suite.ref = "localhost:5000/walk:test"
+	suite.files = map[string]string{
+		"docs/readme.md": "read me",
+		"docs/notes.txt": "some notes",
+		"bin/tool":       "binary",
+		"docs/design.md": "design",
+	}
+	suite.store = orascontent.NewMemory()
+	var descs []ocispec.Descriptor
+	for name, content := range suite.files {
+		desc, err := suite.store.Add(name, "", []byte(content))
+		suite.Nil(err, "no error adding file")
+		descs = append(descs, desc)
+	}
+	manifest, manifestDesc, config, configDesc, err := orascontent.GenerateManifestAndConfig(nil, nil, descs...)
+	suite.Nil(err, "no error generating manifest")
+	suite.store.Set(configDesc, config)
+	err = suite.store.StoreManifest(suite.ref, manifestDesc, manifest)
+	suite.Nil(err, "no error storing manifest")
+}
+
+func (suite *WalkTestSuite) Test_0_Walk() {
+	ctx := context.Background()
+	listDesc, manifestDesc, configDesc, layer := storeDockerImage(suite.Assertions, suite.store, "walk:list")
+
+	// walkRef records the digests visited, and checks the nodes
+	walkRef := func(ref string, opts ...WalkOpt) ([]digest.Digest, error) {
+		var (
+			lock    sync.Mutex
+			visited []digest.Digest
+		)
+		err := Walk(ctx, suite.store, ref, func(ctx context.Context, node Node) error {
+			suite.Equal(len(node.Parents), node.Depth, "depth of %s", node.Descriptor.Digest)
+			switch node.Descriptor.Digest {
+			case listDesc.Digest:
+				suite.IsType(&ocispec.Index{}, node.Manifest, "list parsed")
+				suite.Equal([]ocispec.Descriptor{manifestDesc}, node.Children, "list children")
+			case manifestDesc.Digest:
+				suite.IsType(&artifact.ImageManifest{}, node.Manifest, "manifest parsed")
+				suite.Equal([]ocispec.Descriptor{configDesc, layer}, node.Children, "manifest children")
+				suite.Len(node.Parents, 1, "manifest parents")
+			case configDesc.Digest, layer.Digest:
+				suite.Nil(node.Manifest, "blob not parsed")
+				suite.Nil(node.Content, "blob not fetched")
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			visited = append(visited, node.Descriptor.Digest)
+			return nil
+		}, opts...)
+		return visited, err
+	}
+
+	visited, err := walkRef("walk:list")
+	suite.Nil(err, "no error walking")
+	suite.Equal([]digest.Digest{listDesc.Digest, manifestDesc.Digest, configDesc.Digest, layer.Digest}, visited, "pre-order")
+	visited, err = walkRef("walk:list", WithWalkPostOrder())
+	suite.Nil(err, "no error walking in post-order")
+	suite.Equal([]digest.Digest{configDesc.Digest, layer.Digest, manifestDesc.Digest, listDesc.Digest}, visited, "post-order")
+	visited, err = walkRef("walk:list", WithWalkBFS(), WithWalkPostOrder())
+	suite.Nil(err, "no error walking breadth-first in post-order")
+	suite.Equal([]digest.Digest{configDesc.Digest, layer.Digest, manifestDesc.Digest, listDesc.Digest}, visited, "breadth-first post-order")
+	visited, err = walkRef("walk:list", WithWalkMaxDepth(1))
+	suite.Nil(err, "no error walking with max depth")
+	suite.Equal([]digest.Digest{listDesc.Digest, manifestDesc.Digest}, visited, "max depth")
+	visited, err = walkRef("walk:list", WithWalkMediaTypes(images.MediaTypeDockerSchema2Config))
+	suite.Nil(err, "no error walking with media types")
+	suite.Equal([]digest.Digest{listDesc.Digest, manifestDesc.Digest, configDesc.Digest}, visited, "media types")
+	visited, err = walkRef("walk:list", WithWalkConcurrency(4))
+	suite.Nil(err, "no error walking concurrently")
+	suite.Len(visited, 4, "all visited concurrently")
+	suite.Equal([]digest.Digest{listDesc.Digest, manifestDesc.Digest}, visited[:2], "parents visited first")
+
+	// the goroutines visiting the siblings are bounded by the concurrency
+	var (
+		lock              sync.Mutex
+		running, observed int
+	)
+	err = Walk(ctx, suite.store, suite.ref, func(ctx context.Context, node Node) error {
+		lock.Lock()
+		running++
+		if running > observed {
+			observed = running
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return nil
+	}, WithWalkConcurrency(2))
+	suite.Nil(err, "no error walking with bounded concurrency")
+	suite.LessOrEqual(observed, 3, "at most 2 goroutines besides the caller")
+
+	// breadth-first visits the files of the manifest after the manifest, in order
+	_, root, err := suite.store.Resolve(ctx, suite.ref)
+	suite.Nil(err, "no error resolving")
+	var bfs []ocispec.Descriptor
+	err = Walk(ctx, suite.store, suite.ref, func(ctx context.Context, node Node) error {
+		bfs = append(bfs, node.Descriptor)
+		if node.Depth == 0 {
+			suite.Equal(root, node.Descriptor, "root visited first")
+			suite.Len(node.Children, len(suite.files)+1, "config and files")
+		}
+		return nil
+	}, WithWalkBFS())
+	suite.Nil(err, "no error walking breadth-first")
+	suite.Len(bfs, len(suite.files)+2, "manifest, config and files visited")
+
+	// the children are skipped on demand
+	visited = nil
+	err = Walk(ctx, suite.store, "walk:list", func(ctx context.Context, node Node) error {
+		visited = append(visited, node.Descriptor.Digest)
+		if node.Descriptor.Digest == manifestDesc.Digest {
+			return ErrSkipChildren
+		}
+		return nil
+	})
+	suite.Nil(err, "no error skipping children")
+	suite.Equal([]digest.Digest{listDesc.Digest, manifestDesc.Digest}, visited, "children skipped")
+
+	// a manifest referenced twice is visited once
+	index, err := json.Marshal(artifact.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []artifact.Descriptor{{Descriptor: manifestDesc}, {Descriptor: manifestDesc}},
+	})
+	suite.Nil(err, "no error marshaling index")
+	indexDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(index),
+		Size:      int64(len(index)),
+	}
+	suite.Nil(suite.store.StoreManifest("walk:index", indexDesc, index), "no error storing index")
+	for _, opt := range []WalkOpt{WithWalkMaxDepth(3), WithWalkBFS()} {
+		visited, err = walkRef("walk:index", opt)
+		suite.Nil(err, "no error walking index")
+		suite.Len(visited, 4, "shared manifest visited once")
+	}
+
+	// an index referencing itself is a cycle
+	cycleDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromString("cycle"),
+	}
+	cycle, err := json.Marshal(ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Manifests: []ocispec.Descriptor{manifestDesc, cycleDesc},
+	})
+	suite.Nil(err, "no error marshaling cycle")
+	cycleDesc.Size = int64(len(cycle))
+	fetcher := remotes.FetcherFunc(func(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+		if desc.Digest == cycleDesc.Digest {
+			return ioutil.NopCloser(bytes.NewReader(cycle)), nil
+		}
+		return suite.store.Fetch(ctx, desc)
+	})
+	noop := func(ctx context.Context, node Node) error { return nil }
+	for _, opts := range [][]WalkOpt{{}, {WithWalkBFS()}, {WithWalkConcurrency(2)}} {
+		opt := walkOptsDefaults()
+		for _, o := range opts {
+			suite.Nil(o(opt), "no error applying option")
+		}
+		err = walk(ctx, fetcher, cycleDesc, noop, opt)
+		suite.True(errors.Is(err, ErrCycleDetected), "cycle detected: %v", err)
+	}
+
+	suite.Equal(ErrFromTargetUndefined, Walk(ctx, nil, "walk:list", noop), "error without target")
+	suite.NotNil(Walk(ctx, suite.store, "walk:list", nil), "error without walk func")
+	suite.NotNil(Walk(ctx, suite.store, "walk:list", noop, WithWalkMaxDepth(-1)), "error with negative depth")
+	suite.NotNil(Walk(ctx, suite.store, "walk:list", noop, WithWalkConcurrency(0)), "error without concurrency")
+}
+
+func TestWalkTestSuite(t *testing.T) {
+	suite.Run(t, new(WalkTestSuite))
+}
@@ -0,0 +1,61 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Policy decides what may be copied. It is evaluated against the source
+// reference, then each descriptor before it is transferred, and the content of
+// each manifest before its children are. An error stops the copy, and should be
+// a *Violation when the policy rejects the content.
+type Policy interface {
+	// CheckReference checks the source reference of a copy
+	CheckReference(ctx context.Context, ref string) error
+	// CheckDescriptor checks a descriptor, manifest or blob, before its
+	// content is fetched
+	CheckDescriptor(ctx context.Context, desc ocispec.Descriptor) error
+	// CheckManifest checks the content of a manifest or an index
+	CheckManifest(ctx context.Context, desc ocispec.Descriptor, manifest []byte) error
+}
+
+// Violation is the error of a rule rejecting a reference or a descriptor
+type Violation struct {
+	// Rule is the name of the rule violated
+	Rule string
+	// Ref is the reference rejected, if any
+	Ref string
+	// Descriptor is the descriptor rejected, if any
+	Descriptor *ocispec.Descriptor
+	// Reason tells why the rule rejects it
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	subject := v.Ref
+	if v.Descriptor != nil {
+		subject = fmt.Sprintf("%s %s", v.Descriptor.MediaType, v.Descriptor.Digest)
+	}
+	return fmt.Sprintf("policy violation: %s: %s: %s", v.Rule, subject, v.Reason)
+}
+
+// Unwrap lets errors.Is match ErrPolicyViolation
+func (v *Violation) Unwrap() error {
+	return ErrPolicyViolation
+}
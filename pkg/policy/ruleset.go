@@ -0,0 +1,163 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/containerd/containerd/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"oras.land/oras-go/pkg/content"
+)
+
+// Rule names of a RuleSet
+const (
+	RuleAllowedRegistries   = "allowedRegistries"
+	RuleAllowedMediaTypes   = "allowedMediaTypes"
+	RuleDeniedMediaTypes    = "deniedMediaTypes"
+	RuleRequiredAnnotations = "requiredAnnotations"
+	RuleMaxLayerSize        = "maxLayerSize"
+)
+
+// RuleSet is the built-in Policy, made of rules which apply when set
+type RuleSet struct {
+	// AllowedRegistries lists the registries to copy from, either hosts such
+	// as "registry.example.com" or repository prefixes such as
+	// "registry.example.com/team". References without a registry are rejected.
+	AllowedRegistries []string `json:"allowedRegistries,omitempty" yaml:"allowedRegistries,omitempty"`
+	// AllowedMediaTypes lists the media types of the descriptors to copy, as
+	// patterns of path.Match such as "application/vnd.oci.*"
+	AllowedMediaTypes []string `json:"allowedMediaTypes,omitempty" yaml:"allowedMediaTypes,omitempty"`
+	// DeniedMediaTypes lists the media types of the descriptors to reject, as
+	// patterns of path.Match. They take precedence over the allowed ones.
+	DeniedMediaTypes []string `json:"deniedMediaTypes,omitempty" yaml:"deniedMediaTypes,omitempty"`
+	// RequiredAnnotations lists the annotations every manifest and index must have
+	RequiredAnnotations []string `json:"requiredAnnotations,omitempty" yaml:"requiredAnnotations,omitempty"`
+	// MaxLayerSize is the size in bytes no blob may exceed, 0 for no limit
+	MaxLayerSize int64 `json:"maxLayerSize,omitempty" yaml:"maxLayerSize,omitempty"`
+}
+
+// LoadRuleSet reads a rule set from a JSON or YAML file, as told by its
+// extension: .json, .yaml or .yml
+func LoadRuleSet(filename string) (*RuleSet, error) {
+	p, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var rules RuleSet
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(p))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&rules)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(p, &rules)
+	default:
+		return nil, errors.Wrap(ErrUnknownFormat, filename)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, filename)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, errors.Wrap(err, filename)
+	}
+	return &rules, nil
+}
+
+// Validate checks the patterns and the limits of the rule set
+func (r *RuleSet) Validate() error {
+	for _, patterns := range [][]string{r.AllowedMediaTypes, r.DeniedMediaTypes} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return errors.Wrap(err, pattern)
+			}
+		}
+	}
+	if r.MaxLayerSize < 0 {
+		return fmt.Errorf("%s must not be negative", RuleMaxLayerSize)
+	}
+	return nil
+}
+
+// CheckReference checks the registry of the reference
+func (r *RuleSet) CheckReference(ctx context.Context, ref string) error {
+	if len(r.AllowedRegistries) == 0 {
+		return nil
+	}
+	spec, err := reference.Parse(ref)
+	if err != nil {
+		return &Violation{Rule: RuleAllowedRegistries, Ref: ref, Reason: "no registry"}
+	}
+	for _, allowed := range r.AllowedRegistries {
+		allowed = strings.TrimSuffix(allowed, "/")
+		if spec.Locator == allowed || spec.Hostname() == allowed || strings.HasPrefix(spec.Locator, allowed+"/") {
+			return nil
+		}
+	}
+	return &Violation{Rule: RuleAllowedRegistries, Ref: ref, Reason: fmt.Sprintf("registry %s not allowed", spec.Hostname())}
+}
+
+// CheckDescriptor checks the media type of the descriptor, and the size of
+// blobs
+func (r *RuleSet) CheckDescriptor(ctx context.Context, desc ocispec.Descriptor) error {
+	if matchMediaType(desc.MediaType, r.DeniedMediaTypes) {
+		return &Violation{Rule: RuleDeniedMediaTypes, Descriptor: &desc, Reason: "media type denied"}
+	}
+	if len(r.AllowedMediaTypes) > 0 && !matchMediaType(desc.MediaType, r.AllowedMediaTypes) {
+		return &Violation{Rule: RuleAllowedMediaTypes, Descriptor: &desc, Reason: "media type not allowed"}
+	}
+	if r.MaxLayerSize > 0 && !content.IsManifestMediaType(desc.MediaType) && desc.Size > r.MaxLayerSize {
+		return &Violation{Rule: RuleMaxLayerSize, Descriptor: &desc, Reason: fmt.Sprintf("size %d exceeds %d", desc.Size, r.MaxLayerSize)}
+	}
+	return nil
+}
+
+// CheckManifest checks the annotations of the manifest
+func (r *RuleSet) CheckManifest(ctx context.Context, desc ocispec.Descriptor, manifest []byte) error {
+	if len(r.RequiredAnnotations) == 0 {
+		return nil
+	}
+	var fields struct {
+		Annotations map[string]string `json:"annotations"`
+	}
+	if err := json.Unmarshal(manifest, &fields); err != nil {
+		return err
+	}
+	for _, annotation := range r.RequiredAnnotations {
+		if _, ok := fields.Annotations[annotation]; !ok {
+			return &Violation{Rule: RuleRequiredAnnotations, Descriptor: &desc, Reason: fmt.Sprintf("annotation %s missing", annotation)}
+		}
+	}
+	return nil
+}
+
+// matchMediaType reports whether the media type matches one of the patterns
+func matchMediaType(mediaType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, mediaType); matched {
+			return true
+		}
+	}
+	return false
+}
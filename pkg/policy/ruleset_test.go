@@ -0,0 +1,149 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package policy_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/policy"
+)
+
+func TestLoadRuleSet(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "oras_policy_test")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	expected := policy.RuleSet{
+		AllowedRegistries:   []string{"registry.example.com"},
+		DeniedMediaTypes:    []string{"application/vnd.docker.*"},
+		RequiredAnnotations: []string{"org.example.pipeline"},
+		MaxLayerSize:        2 << 30,
+	}
+	files := map[string]string{
+		"rules.json": `{
+	"allowedRegistries": ["registry.example.com"],
+	"deniedMediaTypes": ["application/vnd.docker.*"],
+	"requiredAnnotations": ["org.example.pipeline"],
+	"maxLayerSize": 2147483648
+}`,
+		"rules.yaml": `allowedRegistries:
+- registry.example.com
+deniedMediaTypes:
+- application/vnd.docker.*
+requiredAnnotations:
+- org.example.pipeline
+maxLayerSize: 2147483648
+`,
+		"rules.txt":     `{}`,
+		"unknown.yaml":  "deniedRegistries: [registry.example.com]\n",
+		"unknown.json":  `{"allowedRegistry": ["registry.example.com"]}`,
+		"invalid.json":  `{"allowedMediaTypes": ["["]}`,
+		"negative.json": `{"maxLayerSize": -1}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(tempDir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("could not write %s: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"rules.json", "rules.yaml"} {
+		rules, err := policy.LoadRuleSet(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("unexpected error loading %s: %v", name, err)
+		}
+		if !reflect.DeepEqual(*rules, expected) {
+			t.Errorf("%s: got %+v, expected %+v", name, rules, expected)
+		}
+	}
+	if _, err := policy.LoadRuleSet(filepath.Join(tempDir, "rules.txt")); !errors.Is(err, policy.ErrUnknownFormat) {
+		t.Errorf("expected unknown format error, got %v", err)
+	}
+	for _, name := range []string{"unknown.yaml", "unknown.json", "invalid.json", "negative.json", "missing.json"} {
+		if _, err := policy.LoadRuleSet(filepath.Join(tempDir, name)); err == nil {
+			t.Errorf("%s: expected error loading rule set", name)
+		}
+	}
+}
+
+func TestRuleSet(t *testing.T) {
+	ctx := context.Background()
+	rules := &policy.RuleSet{
+		AllowedRegistries:   []string{"registry.example.com", "localhost:5000/team/"},
+		AllowedMediaTypes:   []string{"application/vnd.oci.*", "text/plain"},
+		DeniedMediaTypes:    []string{"application/vnd.oci.image.layer.nondistributable.*"},
+		RequiredAnnotations: []string{"org.example.pipeline"},
+		MaxLayerSize:        10,
+	}
+
+	references := map[string]bool{
+		"registry.example.com/app:v1":      true,
+		"localhost:5000/team/app:v1":       true,
+		"localhost:5000/other/app:v1":      false,
+		"registry.example.com.evil/app:v1": false,
+		"app":                              false,
+	}
+	for ref, allowed := range references {
+		err := rules.CheckReference(ctx, ref)
+		if allowed && err != nil {
+			t.Errorf("%s: unexpected error %v", ref, err)
+		}
+		if !allowed && !errors.Is(err, policy.ErrPolicyViolation) {
+			t.Errorf("%s: expected violation, got %v", ref, err)
+		}
+	}
+
+	descriptors := []struct {
+		desc ocispec.Descriptor
+		rule string
+	}{
+		{ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Size: 10}, ""},
+		{ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Size: 100}, ""},
+		{ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Size: 11}, policy.RuleMaxLayerSize},
+		{ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerNonDistributableGzip, Size: 1}, policy.RuleDeniedMediaTypes},
+		{ocispec.Descriptor{MediaType: "application/vnd.docker.image.rootfs.diff.tar.gzip", Size: 1}, policy.RuleAllowedMediaTypes},
+	}
+	for _, tt := range descriptors {
+		err := rules.CheckDescriptor(ctx, tt.desc)
+		if tt.rule == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.desc.MediaType, err)
+			}
+			continue
+		}
+		violation, ok := err.(*policy.Violation)
+		if !ok || violation.Rule != tt.rule || violation.Descriptor == nil {
+			t.Errorf("%s: expected violation of %s, got %v", tt.desc.MediaType, tt.rule, err)
+		}
+	}
+
+	desc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest}
+	if err := rules.CheckManifest(ctx, desc, []byte(`{"annotations":{"org.example.pipeline":"42"}}`)); err != nil {
+		t.Errorf("unexpected error checking annotated manifest: %v", err)
+	}
+	err := rules.CheckManifest(ctx, desc, []byte(`{"annotations":{}}`))
+	if violation, ok := err.(*policy.Violation); !ok || violation.Rule != policy.RuleRequiredAnnotations {
+		t.Errorf("expected violation of %s, got %v", policy.RuleRequiredAnnotations, err)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// LoadPrivateKey reads an ECDSA or Ed25519 private key from a PEM file, either
+// PKCS #8 ("PRIVATE KEY") or SEC 1 ("EC PRIVATE KEY")
+func LoadPrivateKey(filename string) (crypto.Signer, error) {
+	block, err := readPEM(filename)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, filename)
+	}
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, errors.Wrapf(ErrUnsupportedKey, "%s: %T", filename, key)
+}
+
+// LoadPublicKey reads an ECDSA or Ed25519 public key from a PKIX PEM file
+// ("PUBLIC KEY")
+func LoadPublicKey(filename string) (crypto.PublicKey, error) {
+	block, err := readPEM(filename)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, filename)
+	}
+	if err := checkPublicKey(key); err != nil {
+		return nil, errors.Wrap(err, filename)
+	}
+	return key, nil
+}
+
+// KeyID returns the identifier of a public key, the hex encoded SHA-256 of its
+// PKIX form
+func KeyID(key crypto.PublicKey) (string, error) {
+	if err := checkPublicKey(key); err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkPublicKey checks that the public key is of a supported type
+func checkPublicKey(key crypto.PublicKey) error {
+	switch key.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return nil
+	}
+	return errors.Wrapf(ErrUnsupportedKey, "%T", key)
+}
+
+// readPEM reads the first PEM block of a file
+func readPEM(filename string) (*pem.Block, error) {
+	p, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(p)
+	if block == nil {
+		return nil, errors.Wrap(ErrNoPEMBlock, filename)
+	}
+	return block, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+
+	orascontent "oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/oras"
+	"oras.land/oras-go/pkg/target"
+)
+
+const (
+	// ArtifactType is the artifact type of the signature manifests
+	ArtifactType = "application/vnd.oras.signature.v1"
+
+	// MediaTypeEnvelope is the media type of the blob of a signature manifest,
+	// holding the Envelope
+	MediaTypeEnvelope = "application/vnd.oras.signature.envelope.v1+json"
+
+	// envelopeName is the name of the blob of a signature manifest
+	envelopeName = "signature.json"
+
+	// artifactRef is the reference of the manifest pushed by PushArtifact in
+	// its memory store
+	artifactRef = "artifact"
+)
+
+// Envelope is a signature of the descriptor of a manifest
+type Envelope struct {
+	// Payload is the JSON of the media type, digest and size of the manifest
+	Payload []byte `json:"payload"`
+	// Signature is the signature of the payload: ASN.1 encoded over its
+	// SHA-256 for ECDSA keys, over the payload itself for Ed25519 keys
+	Signature []byte `json:"signature"`
+	// KeyID identifies the public key verifying the signature, see KeyID
+	KeyID string `json:"keyid"`
+}
+
+// Sign signs the descriptor of the subject manifest with the key, and pushes
+// the signature as a referrer of the subject, with an image manifest whose
+// artifactType is ArtifactType, to the repository of the target. The
+// repository is a reference without tag nor digest, and may be empty for
+// targets without repositories such as the Memory and OCI stores. Returns the
+// descriptor of the signature manifest.
+func Sign(ctx context.Context, to target.Target, repository string, subject ocispec.Descriptor, key crypto.Signer, opts ...oras.CopyOpt) (ocispec.Descriptor, error) {
+	p, err := payload(subject)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	keyID, err := KeyID(key.Public())
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	signature, err := SignBytes(key, p)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	envelope, err := json.Marshal(Envelope{
+		Payload:   p,
+		Signature: signature,
+		KeyID:     keyID,
+	})
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	envelopeDesc := ocispec.Descriptor{
+		MediaType: MediaTypeEnvelope,
+		Digest:    digest.FromBytes(envelope),
+		Size:      int64(len(envelope)),
+		Annotations: map[string]string{
+			ocispec.AnnotationTitle: envelopeName,
+		},
+	}
+	return PushArtifact(ctx, to, repository, subject, ArtifactType, envelopeDesc, envelope, opts...)
+}
+
+// PushArtifact pushes the blob as a referrer of the subject, with an image
+// manifest of an empty config, the blob as its only layer, and the artifact
+// type, to the repository of the target. The repository is a reference without
+// tag nor digest, and may be empty for targets without repositories such as
+// the Memory and OCI stores. Returns the descriptor of the pushed manifest.
+func PushArtifact(ctx context.Context, to target.Target, repository string, subject ocispec.Descriptor, artifactType string, blob ocispec.Descriptor, p []byte, opts ...oras.CopyOpt) (ocispec.Descriptor, error) {
+	store := orascontent.NewMemory()
+	store.Set(blob, p)
+	config, configDesc, err := orascontent.GenerateEmptyConfig()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	store.Set(configDesc, config)
+	manifest, manifestDesc, err := orascontent.GenerateManifestWithOpts(nil, nil, []ocispec.Descriptor{blob},
+		orascontent.WithSubject(subject),
+		orascontent.WithArtifactType(artifactType),
+	)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := store.StoreManifest(artifactRef, manifestDesc, manifest); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return oras.PushReferrer(ctx, store, artifactRef, to, repository, opts...)
+}
+
+// payload returns the signed form of the subject: its media type, digest and
+// size
+func payload(subject ocispec.Descriptor) ([]byte, error) {
+	return json.Marshal(ocispec.Descriptor{
+		MediaType: subject.MediaType,
+		Digest:    subject.Digest,
+		Size:      subject.Size,
+	})
+}
+
+// SignBytes signs the bytes with the ECDSA or Ed25519 key: over their SHA-256
+// for ECDSA keys, ASN.1 encoded, over the bytes themselves for Ed25519 keys
+func SignBytes(key crypto.Signer, p []byte) ([]byte, error) {
+	switch key.Public().(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(p)
+		return key.Sign(rand.Reader, sum[:], crypto.SHA256)
+	case ed25519.PublicKey:
+		return key.Sign(rand.Reader, p, crypto.Hash(0))
+	}
+	return nil, errors.Wrapf(ErrUnsupportedKey, "%T", key)
+}
+
+// VerifyBytes reports whether the signature is a signature of the bytes by the
+// ECDSA or Ed25519 key, as signed by SignBytes
+func VerifyBytes(key crypto.PublicKey, p, signature []byte) bool {
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(p)
+		return ecdsa.VerifyASN1(key, sum[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, p, signature)
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package signature_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	orascontent "oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/oras"
+	"oras.land/oras-go/pkg/signature"
+)
+
+// writePEM writes a PEM block to a file of the directory
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	filename := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("could not write %s: %v", name, err)
+	}
+	return filename
+}
+
+// generateKeys writes ECDSA and Ed25519 key pairs to the directory, and loads
+// them back
+func generateKeys(t *testing.T, dir string) ([]crypto.Signer, []crypto.PublicKey) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate ECDSA key: %v", err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("could not marshal ECDSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate Ed25519 key: %v", err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatalf("could not marshal Ed25519 key: %v", err)
+	}
+
+	var (
+		signers []crypto.Signer
+		keys    []crypto.PublicKey
+	)
+	for name, key := range map[string]struct {
+		blockType string
+		der       []byte
+		public    crypto.PublicKey
+	}{
+		"ecdsa":   {"EC PRIVATE KEY", ecDER, ecKey.Public()},
+		"ed25519": {"PRIVATE KEY", edDER, edKey.Public()},
+	} {
+		signer, err := signature.LoadPrivateKey(writePEM(t, dir, name+".key", key.blockType, key.der))
+		if err != nil {
+			t.Fatalf("could not load %s private key: %v", name, err)
+		}
+		publicDER, err := x509.MarshalPKIXPublicKey(key.public)
+		if err != nil {
+			t.Fatalf("could not marshal %s public key: %v", name, err)
+		}
+		public, err := signature.LoadPublicKey(writePEM(t, dir, name+".pub", "PUBLIC KEY", publicDER))
+		if err != nil {
+			t.Fatalf("could not load %s public key: %v", name, err)
+		}
+		signers = append(signers, signer)
+		keys = append(keys, public)
+	}
+	return signers, keys
+}
+
+// storeImage stores an image with a single layer in the store under the ref
+func storeImage(t *testing.T, store *orascontent.Memory, ref, layer string) ocispec.Descriptor {
+	layerDesc, err := store.Add(layer, "", []byte(layer))
+	if err != nil {
+		t.Fatalf("could not add layer: %v", err)
+	}
+	manifest, manifestDesc, config, configDesc, err := orascontent.GenerateManifestAndConfig(nil, nil, layerDesc)
+	if err != nil {
+		t.Fatalf("could not generate manifest: %v", err)
+	}
+	store.Set(configDesc, config)
+	if err := store.StoreManifest(ref, manifestDesc, manifest); err != nil {
+		t.Fatalf("could not store manifest: %v", err)
+	}
+	return manifestDesc
+}
+
+func TestLoadKey(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "oras_signature_test")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if _, err := signature.LoadPrivateKey(filepath.Join(tempDir, "missing.key")); err == nil {
+		t.Error("loaded a missing private key")
+	}
+	notPEM := filepath.Join(tempDir, "invalid.pub")
+	if err := ioutil.WriteFile(notPEM, []byte("not a key"), 0600); err != nil {
+		t.Fatalf("could not write invalid key: %v", err)
+	}
+	if _, err := signature.LoadPublicKey(notPEM); !errors.Is(err, signature.ErrNoPEMBlock) {
+		t.Errorf("public key without PEM block error = %v, expected %v", err, signature.ErrNoPEMBlock)
+	}
+	if _, err := signature.KeyID("not a key"); !errors.Is(err, signature.ErrUnsupportedKey) {
+		t.Errorf("KeyID of unsupported key error = %v, expected %v", err, signature.ErrUnsupportedKey)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	ctx := context.Background()
+	tempDir, err := ioutil.TempDir("", "oras_signature_test")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	signers, keys := generateKeys(t, tempDir)
+	_, untrusted := generateKeys(t, tempDir)
+
+	for i, signer := range signers {
+		store := orascontent.NewMemory()
+		subject := storeImage(t, store, "image:signed", "signed")
+		unsigned := storeImage(t, store, "image:unsigned", "unsigned")
+
+		desc, err := signature.Sign(ctx, store, "", subject, signer)
+		if err != nil {
+			t.Fatalf("%T: could not sign: %v", signer, err)
+		}
+		if desc.Digest == "" {
+			t.Errorf("%T: no signature manifest descriptor", signer)
+		}
+
+		verifier, err := signature.NewVerifier(keys[i])
+		if err != nil {
+			t.Fatalf("%T: could not create verifier: %v", signer, err)
+		}
+		if err := verifier.Verify(ctx, store, "image:signed", subject); err != nil {
+			t.Errorf("%T: signature not verified: %v", signer, err)
+		}
+		if err := verifier.Verify(ctx, store, "image:unsigned", unsigned); !errors.Is(err, signature.ErrNoValidSignature) {
+			t.Errorf("%T: unsigned error = %v, expected %v", signer, err, signature.ErrNoValidSignature)
+		}
+		untrustedVerifier, err := signature.NewVerifier(untrusted...)
+		if err != nil {
+			t.Fatalf("%T: could not create verifier: %v", signer, err)
+		}
+		if err := untrustedVerifier.Verify(ctx, store, "image:signed", subject); !errors.Is(err, signature.ErrNoValidSignature) {
+			t.Errorf("%T: untrusted key error = %v, expected %v", signer, err, signature.ErrNoValidSignature)
+		}
+
+		// copy only the signed image with its signature verified
+		for ref, expected := range map[string]error{
+			"image:signed":   nil,
+			"image:unsigned": signature.ErrNoValidSignature,
+		} {
+			to := orascontent.NewMemory()
+			_, err := oras.Copy(ctx, store, ref, to, ref, oras.WithSourceVerification(verifier.Verify))
+			if !errors.Is(err, expected) {
+				t.Errorf("%T: copy of %s error = %v, expected %v", signer, ref, err, expected)
+			}
+			if _, _, err := to.Resolve(ctx, ref); (err == nil) != (expected == nil) {
+				t.Errorf("%T: copy of %s resolve error = %v", signer, ref, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package signature
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/remotes"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+
+	"oras.land/oras-go/pkg/artifact"
+	orascontent "oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/oras"
+	"oras.land/oras-go/pkg/target"
+)
+
+// maxSignatureSize is the size over which the signature manifests and
+// envelopes are not fetched
+const maxSignatureSize = 4 * 1024 * 1024
+
+// Verifier verifies the signatures of manifests against trusted public keys
+type Verifier struct {
+	keys map[string]crypto.PublicKey
+}
+
+// NewVerifier creates a verifier trusting the ECDSA and Ed25519 public keys
+func NewVerifier(keys ...crypto.PublicKey) (*Verifier, error) {
+	v := &Verifier{
+		keys: make(map[string]crypto.PublicKey, len(keys)),
+	}
+	for _, key := range keys {
+		keyID, err := KeyID(key)
+		if err != nil {
+			return nil, err
+		}
+		v.keys[keyID] = key
+	}
+	return v, nil
+}
+
+// Verify discovers the signatures of the subject manifest in the target, and
+// succeeds if one of them is a valid signature of a trusted key. Otherwise, it
+// returns ErrNoValidSignature with the reasons each signature was rejected.
+// The target must implement target.Discoverer. Verify can be passed to
+// oras.WithSourceVerification to verify the source of a copy.
+func (v *Verifier) Verify(ctx context.Context, from target.Target, ref string, subject ocispec.Descriptor) error {
+	discoverer, ok := from.(target.Discoverer)
+	if !ok {
+		return errors.Wrapf(ErrNoValidSignature, "%s: target cannot discover signatures", subject.Digest)
+	}
+	referrers, err := discoverer.Discover(ctx, ref, subject, ArtifactType)
+	if err != nil {
+		return err
+	}
+	if len(referrers) == 0 {
+		return errors.Wrapf(ErrNoValidSignature, "%s: no signature", subject.Digest)
+	}
+	fetcher, err := from.Fetcher(ctx, ref)
+	if err != nil {
+		return err
+	}
+	fetcher = orascontent.NewVerifyingFetcher(fetcher)
+
+	reasons := make([]string, 0, len(referrers))
+	for _, referrer := range referrers {
+		err := v.verifySignature(ctx, fetcher, referrer.Descriptor, subject)
+		if err == nil {
+			return nil
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %v", referrer.Digest, err))
+	}
+	return errors.Wrapf(ErrNoValidSignature, "%s: %s", subject.Digest, strings.Join(reasons, "; "))
+}
+
+// verifySignature verifies a signature manifest of the subject
+func (v *Verifier) verifySignature(ctx context.Context, fetcher remotes.Fetcher, desc, subject ocispec.Descriptor) error {
+	var manifest artifact.ImageManifest
+	if err := FetchJSON(ctx, fetcher, desc, maxSignatureSize, &manifest); err != nil {
+		return err
+	}
+	if manifest.Subject == nil || manifest.Subject.Digest != subject.Digest {
+		return errors.New("not a signature of the subject")
+	}
+	var envelope *ocispec.Descriptor
+	for i, layer := range manifest.Layers {
+		if layer.MediaType == MediaTypeEnvelope {
+			envelope = &manifest.Layers[i]
+			break
+		}
+	}
+	if envelope == nil {
+		return errors.New("no signature envelope")
+	}
+	var signature Envelope
+	if err := FetchJSON(ctx, fetcher, *envelope, maxSignatureSize, &signature); err != nil {
+		return err
+	}
+
+	key, ok := v.keys[signature.KeyID]
+	if !ok {
+		return fmt.Errorf("untrusted key %s", signature.KeyID)
+	}
+	var signed ocispec.Descriptor
+	if err := json.Unmarshal(signature.Payload, &signed); err != nil {
+		return err
+	}
+	if signed.Digest != subject.Digest || signed.Size != subject.Size || signed.MediaType != subject.MediaType {
+		return fmt.Errorf("payload signs %s of %d bytes, not the subject", signed.Digest, signed.Size)
+	}
+	if !VerifyBytes(key, signature.Payload, signature.Signature) {
+		return fmt.Errorf("invalid signature of %s with key %s", subject.Digest, signature.KeyID)
+	}
+	return nil
+}
+
+// FetchJSON fetches and decodes the JSON content of a descriptor, refusing
+// the content larger than maxSize
+func FetchJSON(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor, maxSize int64, v interface{}) error {
+	if desc.Size > maxSize {
+		return fmt.Errorf("%s of %d bytes too large", desc.Digest, desc.Size)
+	}
+	p, err := content.ReadBlob(ctx, &oras.ProviderWrapper{Fetcher: fetcher}, desc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(p, v)
+}
@@ -15,7 +15,13 @@ limitations under the License.
 package target
 
 import (
+	"context"
+
 	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"oras.land/oras-go/pkg/artifact"
 )
 
 // Target represents a place to which one can send/push or retrieve/pull artifacts.
@@ -24,3 +30,49 @@ import (
 type Target interface {
 	remotes.Resolver
 }
+
+// Lister is a Target which can list its repositories and tags.
+type Lister interface {
+	// Repositories lists the repositories of the host, sorted.
+	Repositories(ctx context.Context, host string) ([]string, error)
+	// Tags lists the tags of the repository referenced by ref, sorted. The tag
+	// or digest of ref, if any, is ignored.
+	Tags(ctx context.Context, ref string) ([]string, error)
+}
+
+// Deleter is a Target which can delete content.
+type Deleter interface {
+	// DeleteManifest deletes the manifest with the digest from the repository
+	// referenced by ref, along with the tags referencing it.
+	DeleteManifest(ctx context.Context, ref string, dgst digest.Digest) error
+	// DeleteBlob deletes the blob with the digest from the repository
+	// referenced by ref.
+	DeleteBlob(ctx context.Context, ref string, dgst digest.Digest) error
+	// Untag removes the tag of ref, keeping the manifest it references.
+	Untag(ctx context.Context, ref string) error
+}
+
+// Discoverer is a Target which can discover the artifacts referring to a
+// subject.
+type Discoverer interface {
+	// Discover returns the descriptors of the manifests referring to the
+	// subject in the repository referenced by ref. If artifactType is not
+	// empty, only the referrers with that artifact type are returned.
+	Discover(ctx context.Context, ref string, subject ocispec.Descriptor, artifactType string) ([]artifact.Descriptor, error)
+}
+
+// UntaggedPusher is a Target whose pushers tag the root with the reference,
+// which can also push a root without tagging it, such as a referrer.
+type UntaggedPusher interface {
+	// UntaggedPusher returns a pusher to the repository referenced by ref,
+	// which does not tag the root.
+	UntaggedPusher(ctx context.Context, ref string) (remotes.Pusher, error)
+}
+
+// ReferrerIndexer is a Target which keeps an index of the referrers of each
+// subject, to be updated when a referrer is pushed.
+type ReferrerIndexer interface {
+	// IndexReferrer adds the referrer to the index of the referrers of the
+	// subject in the repository referenced by ref.
+	IndexReferrer(ctx context.Context, ref string, subject ocispec.Descriptor, referrer artifact.Descriptor) error
+}